@@ -0,0 +1,335 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// api包提供管理正在监听的项目的HTTP接口（autogo -api :7070）：
+//
+// GET /api/projects 所有项目的状态；GET /api/projects/名称 某个项目的状态；
+// GET /api/projects/名称/build 最近一次编译的输出及解析出的错误；
+// GET /api/projects/名称/logs 项目进程的输出（server-sent events，先发送最近的输出，之后实时发送新的输出）；
+// GET /api/logs 所有项目进程的输出（同上，每一行带有项目名称project，控制台页面只需要一个连接）；
+// POST /api/projects/名称/命令 执行rebuild、restart、start、stop、pause或resume（见project.Project.Control），返回执行后的状态。
+// PUT（或POST）/api/projects/名称/args 修改程序参数（JSON格式的字符串数组，见project.Project.SetArgs）并重新启动，返回执行后的状态。
+// 出错时返回{"error": "错误信息"}。
+//
+// 根路径/是所有项目的控制台页面（templates/dashboard.html）
+package api
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "net/url"
+    "project"
+    "strings"
+    "time"
+)
+
+// Prefix 接口路径的前缀
+const Prefix = "/api/projects"
+
+// LogsPath 所有项目进程的输出
+const LogsPath = "/api/logs"
+
+// dashboardFile 控制台页面（路径相对于autogo的工作目录）
+var dashboardFile = "templates/dashboard.html"
+
+// Handler 控制API的http.Handler
+type Handler struct {
+    registry *project.Registry
+}
+
+func NewHandler(registry *project.Registry) *Handler {
+    return &Handler{registry: registry}
+}
+
+// Listen 在addr上提供控制API。addr没有指定主机（如:7070）时只监听127.0.0.1，接口没有认证，不要暴露到外网
+func Listen(addr string, registry *project.Registry) error {
+    if strings.HasPrefix(addr, ":") {
+        addr = "127.0.0.1" + addr
+    }
+    listener, err := net.Listen("tcp", addr)
+    if err != nil {
+        return err
+    }
+    log.Println("[INFO] 控制台：http://"+listener.Addr().String()+"/", "控制API：http://"+listener.Addr().String()+Prefix)
+    go http.Serve(listener, NewHandler(registry))
+    return nil
+}
+
+func (this *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+    if req.URL.Path == "/" {
+        http.ServeFile(rw, req, dashboardFile)
+        return
+    }
+    if req.URL.Path == LogsPath {
+        if req.Method != "GET" {
+            writeError(rw, http.StatusMethodNotAllowed, "只支持GET")
+            return
+        }
+        this.serveAllLogs(rw, req)
+        return
+    }
+    if req.URL.Path != Prefix && !strings.HasPrefix(req.URL.Path, Prefix+"/") {
+        writeError(rw, http.StatusNotFound, "not found")
+        return
+    }
+    path := strings.Trim(strings.TrimPrefix(req.URL.Path, Prefix), "/")
+    if path == "" {
+        if req.Method != "GET" {
+            writeError(rw, http.StatusMethodNotAllowed, "只支持GET")
+            return
+        }
+        projects := this.registry.Projects()
+        statuses := make([]*project.Status, len(projects))
+        for i, prj := range projects {
+            statuses[i] = prj.Status()
+        }
+        writeJSON(rw, http.StatusOK, statuses)
+        return
+    }
+
+    parts := strings.Split(path, "/")
+    if len(parts) > 2 {
+        writeError(rw, http.StatusNotFound, "not found")
+        return
+    }
+    prj := this.registry.Get(parts[0])
+    if prj == nil {
+        writeError(rw, http.StatusNotFound, "没有名为"+parts[0]+"的项目")
+        return
+    }
+    if len(parts) == 1 {
+        if req.Method != "GET" {
+            writeError(rw, http.StatusMethodNotAllowed, "只支持GET")
+            return
+        }
+        writeJSON(rw, http.StatusOK, prj.Status())
+        return
+    }
+
+    if parts[1] == "args" {
+        serveArgs(rw, req, prj)
+        return
+    }
+    if req.Method == "GET" {
+        switch parts[1] {
+        case "build":
+            writeJSON(rw, http.StatusOK, prj.LastBuild())
+        case "logs":
+            serveLogs(rw, req, prj)
+        default:
+            writeMethodError(rw, parts[1])
+        }
+        return
+    }
+    if req.Method != "POST" {
+        writeMethodError(rw, parts[1])
+        return
+    }
+    if !sameOrigin(req) {
+        // 避免其他网站的页面通过浏览器调用接口
+        writeError(rw, http.StatusForbidden, "不允许跨域调用")
+        return
+    }
+    if err := prj.Control(parts[1]); err != nil {
+        status := http.StatusInternalServerError
+        if !isCommand(parts[1]) {
+            status = http.StatusNotFound
+        }
+        writeError(rw, status, err.Error())
+        return
+    }
+    writeJSON(rw, http.StatusOK, prj.Status())
+}
+
+// serveArgs 修改程序参数，然后重新启动项目（restart命令）
+func serveArgs(rw http.ResponseWriter, req *http.Request, prj *project.Project) {
+    if req.Method != "PUT" && req.Method != "POST" {
+        writeError(rw, http.StatusMethodNotAllowed, "只支持PUT或POST")
+        return
+    }
+    if !sameOrigin(req) {
+        writeError(rw, http.StatusForbidden, "不允许跨域调用")
+        return
+    }
+    var args []string
+    if err := json.NewDecoder(io.LimitReader(req.Body, 1<<20)).Decode(&args); err != nil {
+        writeError(rw, http.StatusBadRequest, "程序参数应该是JSON格式的字符串数组："+err.Error())
+        return
+    }
+    log.Println("[INFO] 项目", prj.Name(), "的程序参数修改为", args)
+    prj.SetArgs(args...)
+    if err := prj.Control("restart"); err != nil {
+        writeError(rw, http.StatusInternalServerError, err.Error())
+        return
+    }
+    writeJSON(rw, http.StatusOK, prj.Status())
+}
+
+// serveLogs 以server-sent events的方式发送项目进程的输出，每一行是一个JSON格式（project.LogLine）的message事件
+func serveLogs(rw http.ResponseWriter, req *http.Request, prj *project.Project) {
+    flusher, ok := rw.(http.Flusher)
+    if !ok {
+        writeError(rw, http.StatusInternalServerError, "streaming unsupported")
+        return
+    }
+    lines, tail, cancel := prj.SubscribeLogs()
+    defer cancel()
+
+    rw.Header().Set("Content-Type", "text/event-stream")
+    rw.Header().Set("Cache-Control", "no-cache")
+    rw.WriteHeader(http.StatusOK)
+    for _, line := range lines {
+        writeEvent(rw, line)
+    }
+    flusher.Flush()
+
+    heartbeat := time.NewTicker(30 * time.Second)
+    defer heartbeat.Stop()
+    for {
+        select {
+        case line, ok := <-tail:
+            if !ok {
+                // 项目已经停止监听（如配置有变化，重新创建了项目），浏览器会重新连接
+                return
+            }
+            writeEvent(rw, line)
+        case <-heartbeat.C:
+            fmt.Fprint(rw, ": ping\n\n")
+        case <-req.Context().Done():
+            return
+        }
+        flusher.Flush()
+    }
+}
+
+// projectLine 所有项目的输出中的一行（见serveAllLogs）
+type projectLine struct {
+    Project string `json:"project"`
+    project.LogLine
+}
+
+// serveAllLogs 以server-sent events的方式发送所有项目进程的输出，每一行是一个JSON格式（projectLine）的message事件。
+// 之后新加入（或者重新加载）的项目每秒检查一次，同样先发送最近的输出
+func (this *Handler) serveAllLogs(rw http.ResponseWriter, req *http.Request) {
+    flusher, ok := rw.(http.Flusher)
+    if !ok {
+        writeError(rw, http.StatusInternalServerError, "streaming unsupported")
+        return
+    }
+    rw.Header().Set("Content-Type", "text/event-stream")
+    rw.Header().Set("Cache-Control", "no-cache")
+    rw.WriteHeader(http.StatusOK)
+
+    done := req.Context().Done()
+    tails := make(chan projectLine, 100)
+    subscribed := make(map[*project.Project]bool)
+    subscribe := func() {
+        projects := make(map[*project.Project]bool)
+        for _, prj := range this.registry.Projects() {
+            projects[prj] = true
+            if subscribed[prj] {
+                continue
+            }
+            subscribed[prj] = true
+            lines, tail, cancel := prj.SubscribeLogs()
+            for _, line := range lines {
+                writeEvent(rw, projectLine{prj.Name(), line})
+            }
+            go func(name string) {
+                defer cancel()
+                for {
+                    select {
+                    case line, ok := <-tail:
+                        if !ok {
+                            // 项目已经停止监听
+                            return
+                        }
+                        select {
+                        case tails <- projectLine{name, line}:
+                        case <-done:
+                            return
+                        }
+                    case <-done:
+                        return
+                    }
+                }
+            }(prj.Name())
+        }
+        // 已经删除的项目
+        for prj := range subscribed {
+            if !projects[prj] {
+                delete(subscribed, prj)
+            }
+        }
+    }
+    subscribe()
+    flusher.Flush()
+
+    check := time.NewTicker(time.Second)
+    defer check.Stop()
+    heartbeat := time.NewTicker(30 * time.Second)
+    defer heartbeat.Stop()
+    for {
+        select {
+        case line := <-tails:
+            writeEvent(rw, line)
+        case <-check.C:
+            subscribe()
+        case <-heartbeat.C:
+            fmt.Fprint(rw, ": ping\n\n")
+        case <-done:
+            return
+        }
+        flusher.Flush()
+    }
+}
+
+func writeEvent(w io.Writer, line interface{}) {
+    data, _ := json.Marshal(line)
+    fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// writeMethodError 命令只支持POST；不是命令时返回404
+func writeMethodError(rw http.ResponseWriter, command string) {
+    if isCommand(command) {
+        writeError(rw, http.StatusMethodNotAllowed, "只支持POST")
+    } else {
+        writeError(rw, http.StatusNotFound, "not found")
+    }
+}
+
+// isCommand 是否是Project.Control支持的命令
+func isCommand(command string) bool {
+    switch command {
+    case "rebuild", "restart", "start", "stop", "pause", "resume":
+        return true
+    }
+    return false
+}
+
+// sameOrigin 请求没有Origin头（如curl），或者Origin和请求的主机相同
+func sameOrigin(req *http.Request) bool {
+    origin := req.Header.Get("Origin")
+    if origin == "" {
+        return true
+    }
+    u, err := url.Parse(origin)
+    return err == nil && u.Host == req.Host
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+    rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+    rw.WriteHeader(status)
+    encoder := json.NewEncoder(rw)
+    encoder.SetIndent("", "  ")
+    encoder.Encode(v)
+}
+
+func writeError(rw http.ResponseWriter, status int, message string) {
+    writeJSON(rw, status, map[string]string{"error": message})
+}
@@ -0,0 +1,161 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+    "bufio"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "project"
+    "strings"
+    "testing"
+)
+
+func init() {
+    dashboardFile = "../../templates/dashboard.html"
+}
+
+func TestHandler(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+    registry := newTestRegistry(t, dir)
+    defer registry.Apply(nil)
+
+    handler := NewHandler(registry)
+    tests := []struct {
+        method, path string
+        code         int
+        body         string
+    }{
+        {"GET", "/", http.StatusOK, "autogo控制台"},
+        {"GET", "/api/projects", http.StatusOK, `"name": "args"`},
+        {"POST", "/api/projects", http.StatusMethodNotAllowed, "只支持GET"},
+        {"GET", "/api/projects/web", http.StatusNotFound, "没有名为web的项目"},
+        {"POST", "/api/projects/web/restart", http.StatusNotFound, "没有名为web的项目"},
+        {"GET", "/api/projects/args/build", http.StatusOK, `"failed": false`},
+        {"GET", "/api/projects/args/restart", http.StatusMethodNotAllowed, "只支持POST"},
+        {"GET", "/api/projects/args/foo", http.StatusNotFound, "not found"},
+        {"PUT", "/api/projects/args/foo", http.StatusNotFound, "not found"},
+        {"POST", "/api/projects/args/foo", http.StatusNotFound, "不支持的命令"},
+        {"POST", "/api/logs", http.StatusMethodNotAllowed, "只支持GET"},
+        {"GET", "/api/other", http.StatusNotFound, "not found"},
+    }
+    for _, test := range tests {
+        req := httptest.NewRequest(test.method, test.path, nil)
+        rec := httptest.NewRecorder()
+        handler.ServeHTTP(rec, req)
+        if rec.Code != test.code || !strings.Contains(rec.Body.String(), test.body) {
+            t.Errorf("%s %s returned %d %s", test.method, test.path, rec.Code, rec.Body.String())
+        }
+    }
+
+    req := httptest.NewRequest("POST", "http://localhost:7070/api/projects/web/stop", nil)
+    if !sameOrigin(req) {
+        t.Error("request without Origin should be allowed")
+    }
+    req.Header.Set("Origin", "http://localhost:7070")
+    if !sameOrigin(req) {
+        t.Error("same origin request should be allowed")
+    }
+    req.Header.Set("Origin", "http://example.com")
+    if sameOrigin(req) {
+        t.Error("cross origin request should be rejected")
+    }
+}
+
+// newTestRegistry 在dir中创建一个名为args的项目（程序把参数写入args.txt，工作目录是项目根目录），编译运行后返回监听它的Registry
+func newTestRegistry(t *testing.T, dir string) *project.Registry {
+    root := filepath.Join(dir, "args")
+    if err := os.MkdirAll(filepath.Join(root, "src"), 0777); err != nil {
+        t.Fatalf("MkdirAll failed: %s", err)
+    }
+    source := "package main\n\nimport (\n    \"io/ioutil\"\n    \"os\"\n    \"strings\"\n)\n\nfunc main() {\n    ioutil.WriteFile(\"args.txt\", []byte(strings.Join(os.Args[1:], \" \")), 0666)\n}\n"
+    if err := ioutil.WriteFile(filepath.Join(root, "src", "args.go"), []byte(source), 0666); err != nil {
+        t.Fatalf("WriteFile failed: %s", err)
+    }
+
+    registry := project.NewRegistry()
+    err := registry.Apply([]project.Spec{{Name: "args", New: func() (*project.Project, error) {
+        prj, err := project.New("args", root, "build", "", false)
+        if err == nil {
+            prj.SetLogFile(project.LogFileOff, nil)
+        }
+        return prj, err
+    }}})
+    if err != nil {
+        t.Fatalf("Apply failed: %s", err)
+    }
+    return registry
+}
+
+func TestSetArgs(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+    root := filepath.Join(dir, "args")
+    registry := newTestRegistry(t, dir)
+    defer registry.Apply(nil)
+
+    handler := NewHandler(registry)
+    req := httptest.NewRequest("PUT", "/api/projects/args/args", strings.NewReader(`["-name", "${NAME}"]`))
+    rec := httptest.NewRecorder()
+    handler.ServeHTTP(rec, req)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("PUT args returned %d %s", rec.Code, rec.Body.String())
+    }
+    data, err := ioutil.ReadFile(filepath.Join(root, "args.txt"))
+    if err != nil || string(data) != "-name args" {
+        t.Errorf("project was not restarted with new args: %q %v", data, err)
+    }
+
+    for _, test := range []struct {
+        method, body string
+        code         int
+    }{
+        {"POST", `"-name"`, http.StatusBadRequest},
+        {"GET", "", http.StatusMethodNotAllowed},
+    } {
+        req = httptest.NewRequest(test.method, "/api/projects/args/args", strings.NewReader(test.body))
+        rec = httptest.NewRecorder()
+        handler.ServeHTTP(rec, req)
+        if rec.Code != test.code {
+            t.Errorf("%s args %s returned %d %s", test.method, test.body, rec.Code, rec.Body.String())
+        }
+    }
+}
+
+func TestAllLogs(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+    registry := newTestRegistry(t, dir)
+    defer registry.Apply(nil)
+
+    server := httptest.NewServer(NewHandler(registry))
+    defer server.Close()
+    resp, err := http.Get(server.URL + LogsPath)
+    if err != nil {
+        t.Fatalf("GET %s failed: %s", LogsPath, err)
+    }
+    defer resp.Body.Close()
+    if resp.Header.Get("Content-Type") != "text/event-stream" {
+        t.Fatalf("unexpected Content-Type %s", resp.Header.Get("Content-Type"))
+    }
+    // 先发送的是项目最近的输出，每一行带有项目名称
+    line, err := bufio.NewReader(resp.Body).ReadString('\n')
+    if err != nil || !strings.HasPrefix(line, `data: {"project":"args",`) {
+        t.Errorf("unexpected event %q %v", line, err)
+    }
+}
@@ -5,20 +5,42 @@
 package main
 
 import (
+    "api"
     "config"
     "flag"
+    "log"
+    "os"
+    "project"
     "runtime"
 )
 
-var configFile string
+var (
+    configFile string
+    check      bool
+    apiAddr    string
+)
 
 func init() {
     runtime.GOMAXPROCS(runtime.NumCPU())
     flag.StringVar(&configFile, "f", "config/projects.json", "配置文件：需要监听哪些工程")
+    flag.BoolVar(&check, "check", false, "只检查配置文件，有错误时以非0状态退出")
+    flag.StringVar(&apiAddr, "api", "", "控制API监听的地址（如:7070，只监听本机），为空时不提供")
     flag.Parse()
 }
 
 func main() {
+    if check {
+        if !config.Check(configFile) {
+            os.Exit(1)
+        }
+        return
+    }
+    if apiAddr != "" {
+        if err := api.Listen(apiAddr, project.DefaultRegistry); err != nil {
+            log.Println("[ERROR] 控制API监听", apiAddr, "失败：", err)
+            os.Exit(1)
+        }
+    }
     config.Load(configFile)
     config.Watch(configFile)
     select {}
@@ -1,16 +1,24 @@
 package config
 
 import (
+    "encoding/json"
+    "errors"
     "fmt"
     "fsnotify"
     "log"
+    "path/filepath"
     "project"
-    "simplejson"
+    "regexp"
     "time"
 )
 
-// Watch 监控配置文件
+// Watch 监控配置文件。监听的是配置文件所在的目录：很多编辑器保存时先写临时文件再重命名，
+// 直接监听文件的话，第一次保存后就监听不到了
 func Watch(configFile string) error {
+    configFile, err := filepath.Abs(configFile)
+    if err != nil {
+        return err
+    }
     watcher, err := fsnotify.NewWatcher()
     if err != nil {
         return err
@@ -22,8 +30,10 @@ func Watch(configFile string) error {
         GetEvent:
             for {
                 select {
-                case <-watcher.Event:
-                    i++
+                case event := <-watcher.Event:
+                    if filepath.Clean(event.Name) == configFile {
+                        i++
+                    }
                 case <-time.After(200e6):
                     break GetEvent
                 }
@@ -44,34 +54,106 @@ func Watch(configFile string) error {
         }
     }()
 
-    return watcher.Watch(configFile)
+    return watcher.Watch(filepath.Dir(configFile))
 }
 
-// Load加载解析配置文件
+// Load加载解析配置文件。重新加载时只处理有变化的项目（见project.Registry）；
+// 配置文件有错误时不做任何改动
 func Load(configFile string) error {
-    allConfig, err := simplejson.ParseFile(configFile)
-    if err != nil {
-        log.Println("[ERROR] 配置文件格式错误", err)
-        return err
+    configs, problems := Parse(configFile)
+    for _, problem := range problems {
+        log.Println(problem)
     }
-    middleJs, err := allConfig.Array()
-    if err != nil {
-        log.Println("[ERROR] 配置文件格式错误", err)
-        return err
+    if problems.HasError() {
+        log.Println("[ERROR] 配置文件有错误，没有加载：", configFile)
+        return errors.New("配置文件有错误：" + configFile)
     }
-    for i, length := 0, len(middleJs); i < length; i++ {
-        oneProject := allConfig.GetIndex(i)
-        name := oneProject.Get("name").MustString()
-        root := oneProject.Get("root").MustString()
-        goWay := oneProject.Get("go_way").MustString()
-        deamon := oneProject.Get("deamon").MustBool(true)
-        mainFile := oneProject.Get("main").MustString()
-        depends := oneProject.GetStringSlice("depends")
-        err = project.Watch(name, root, goWay, mainFile, deamon, depends...)
+    specs := make([]project.Spec, 0, len(configs))
+    for _, cfg := range configs {
+        definition, err := json.Marshal(cfg)
         if err != nil {
-            log.Println("[ERROR] 监控Project：", name, " 出错。详细信息如下：")
-            fmt.Println(err)
+            return err
+        }
+        specs = append(specs, project.Spec{Name: cfg.Name, Definition: string(definition), New: cfg.NewProject})
+    }
+    return project.DefaultRegistry.Apply(specs)
+}
+
+// Check 检查配置文件，输出所有的问题，返回是否有错误
+func Check(configFile string) bool {
+    configs, problems := Parse(configFile)
+    for _, problem := range problems {
+        fmt.Println(problem)
+    }
+    if problems.HasError() {
+        fmt.Println("配置文件有错误：", configFile)
+        return false
+    }
+    fmt.Println("配置文件正确：", configFile, "，共", len(configs), "个项目")
+    return true
+}
+
+// NewProject 按配置创建项目
+func (this *ProjectConfig) NewProject() (*project.Project, error) {
+    // New会修改depends（Go Module项目中转为绝对路径），不能影响配置本身
+    depends := append([]string(nil), this.Depends...)
+    prj, err := project.New(this.Name, this.Root, this.GoWay, this.Main, this.Deamon, depends...)
+    if err != nil {
+        return nil, err
+    }
+    prj.CustomScript = this.CustomScript
+    prj.SetTestOnChange(this.TestOnChange)
+    prj.SetVetOnChange(this.VetOnChange)
+    prj.SetArgs(this.Args...)
+    if err = prj.SetStop(this.StopSignal, this.StopTimeout); err != nil {
+        return nil, err
+    }
+    if err = prj.SetEnv(this.Env, this.EnvFile); err != nil {
+        return nil, err
+    }
+    var rotate *project.LogRotate
+    if this.LogRotate != nil {
+        rotate = &project.LogRotate{MaxSize: this.LogRotate.MaxSize, MaxAge: this.LogRotate.MaxAge, MaxBackups: this.LogRotate.MaxBackups}
+    }
+    prj.SetLogFile(this.LogFile, rotate)
+    if this.Build != nil {
+        prj.SetBuildFlags(&project.BuildFlags{
+            Tags:     this.Build.Tags,
+            Ldflags:  this.Build.Ldflags,
+            Gcflags:  this.Build.Gcflags,
+            Race:     this.Build.Race,
+            Trimpath: this.Build.Trimpath,
+            X:        this.Build.X,
+            Flags:    this.Build.Flags,
+        })
+    }
+    if this.Hooks != nil {
+        prj.SetHooks(&project.Hooks{
+            PreBuild:  this.Hooks.PreBuild,
+            PostBuild: this.Hooks.PostBuild,
+            PreStart:  this.Hooks.PreStart,
+            OnFailure: this.Hooks.OnFailure,
+        })
+    }
+    if this.Watch != nil {
+        prj.SetWatchFilter(this.Watch.Include, this.Watch.Exclude)
+    }
+    if this.Ready != nil {
+        probe := &project.ReadyProbe{
+            TCP:     this.Ready.TCP,
+            HTTP:    this.Ready.HTTP,
+            Alive:   this.Ready.Alive,
+            Timeout: this.Ready.Timeout,
         }
+        if this.Ready.Log != "" {
+            if probe.Log, err = regexp.Compile(this.Ready.Log); err != nil {
+                return nil, err
+            }
+        }
+        prj.SetReady(probe)
+    }
+    if err = prj.SetProxy(this.ProxyListen, this.ProxyTarget, this.LiveReload); err != nil {
+        return nil, err
     }
-    return err
+    return prj, nil
 }
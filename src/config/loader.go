@@ -0,0 +1,152 @@
+package config
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "path/filepath"
+    "simplejson"
+    "strings"
+    "time"
+    "toml"
+    "yaml"
+)
+
+// Decoder 将配置文件的内容解码为项目配置（顶层是项目数组），交给Parse统一检查
+type Decoder func(content []byte) (*simplejson.Json, error)
+
+// decoders 按扩展名选择解码器
+var decoders = map[string]Decoder{
+    ".json":  decodeJSON,
+    ".jsonc": decodeJSON,
+    ".yaml":  decodeYAML,
+    ".yml":   decodeYAML,
+    ".toml":  decodeTOML,
+}
+
+// RegisterDecoder 注册（或替换）某种扩展名（如.yaml）的配置文件解码器
+func RegisterDecoder(ext string, decoder Decoder) {
+    decoders[strings.ToLower(ext)] = decoder
+}
+
+// ReadFile 读取配置文件，按扩展名选择解码器
+func ReadFile(configFile string) (*simplejson.Json, error) {
+    ext := strings.ToLower(filepath.Ext(configFile))
+    decoder, ok := decoders[ext]
+    if !ok {
+        return nil, errors.New("不支持的配置文件格式" + ext + "（支持.json、.jsonc、.yaml、.yml、.toml）")
+    }
+    content, err := ioutil.ReadFile(configFile)
+    if err != nil {
+        return nil, err
+    }
+    js, err := decoder(content)
+    if err != nil {
+        if e, ok := err.(*simplejson.SyntaxError); ok {
+            e.Filename = configFile
+            return nil, e
+        }
+        return nil, fmt.Errorf("%s: %s", configFile, err)
+    }
+    return js, nil
+}
+
+// decodeJSON 带注释的JSON
+func decodeJSON(content []byte) (*simplejson.Json, error) {
+    return simplejson.NewJsonc(content)
+}
+
+// decodeYAML YAML：和JSON一样，顶层是项目数组，每个项目以"- name: xxx"开始
+func decodeYAML(content []byte) (*simplejson.Json, error) {
+    var data interface{}
+    if err := yaml.Unmarshal(content, &data); err != nil {
+        return nil, err
+    }
+    return toJson(data)
+}
+
+// decodeTOML TOML：顶层只能是table，每个项目写在一个[[projects]]中
+func decodeTOML(content []byte) (*simplejson.Json, error) {
+    var data map[string]interface{}
+    if _, err := toml.Decode(string(content), &data); err != nil {
+        return nil, err
+    }
+    for key := range data {
+        if key != "projects" {
+            return nil, errors.New("未知的配置项" + key + "，每个项目应该写在[[projects]]中")
+        }
+    }
+    projects, ok := data["projects"]
+    if !ok {
+        projects = []interface{}{}
+    }
+    return toJson(projects)
+}
+
+// toJson 将YAML、TOML解码的结果转为和JSON相同的结构（对象的key是字符串，数字是float64），
+// 这样所有格式都按同样的规则检查
+func toJson(data interface{}) (*simplejson.Json, error) {
+    normalized, err := normalize(data)
+    if err != nil {
+        return nil, err
+    }
+    content, err := json.Marshal(normalized)
+    if err != nil {
+        return nil, err
+    }
+    return simplejson.NewJson(content)
+}
+
+func normalize(data interface{}) (interface{}, error) {
+    switch v := data.(type) {
+    case map[interface{}]interface{}:
+        m := make(map[string]interface{}, len(v))
+        for key, value := range v {
+            k, ok := key.(string)
+            if !ok {
+                return nil, fmt.Errorf("配置项的名称必须是字符串：%v", key)
+            }
+            normalized, err := normalize(value)
+            if err != nil {
+                return nil, err
+            }
+            m[k] = normalized
+        }
+        return m, nil
+    case map[string]interface{}:
+        m := make(map[string]interface{}, len(v))
+        for key, value := range v {
+            normalized, err := normalize(value)
+            if err != nil {
+                return nil, err
+            }
+            m[key] = normalized
+        }
+        return m, nil
+    case []interface{}:
+        arr := make([]interface{}, len(v))
+        for i, value := range v {
+            normalized, err := normalize(value)
+            if err != nil {
+                return nil, err
+            }
+            arr[i] = normalized
+        }
+        return arr, nil
+    case []map[string]interface{}:
+        arr := make([]interface{}, len(v))
+        for i, value := range v {
+            normalized, err := normalize(value)
+            if err != nil {
+                return nil, err
+            }
+            arr[i] = normalized
+        }
+        return arr, nil
+    case time.Time:
+        return v.Format(time.RFC3339), nil
+    default:
+        return v, nil
+    }
+}
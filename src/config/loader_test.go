@@ -0,0 +1,83 @@
+package config
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestReadFileFormats(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    // 同样的配置，不同的格式
+    contents := map[string]string{
+        "projects.json": `[
+            // 注释
+            {"name": "web", "root": ".", "deamon": false, "stop_timeout": 1.5,
+             "ready": {"tcp": ":8080"}, "watch": {"exclude": ["vendor/"]}},
+        ]`,
+        "projects.yaml": `
+# 注释
+- name: web
+  root: .
+  deamon: false
+  stop_timeout: 1.5
+  ready:
+    tcp: ":8080"
+  watch:
+    exclude: [vendor/]
+`,
+        "projects.toml": `
+# 注释
+[[projects]]
+name = "web"
+root = "."
+deamon = false
+stop_timeout = 1.5
+ready = { tcp = ":8080" }
+
+  [projects.watch]
+  exclude = ["vendor/"]
+`,
+    }
+    var (
+        expected         []*ProjectConfig
+        expectedProblems Problems
+    )
+    for _, name := range []string{"projects.json", "projects.yaml", "projects.toml"} {
+        filename := filepath.Join(dir, name)
+        if err = ioutil.WriteFile(filename, []byte(contents[name]), 0666); err != nil {
+            t.Fatalf("Failed to write %s: %s", name, err)
+        }
+        js, err := ReadFile(filename)
+        if err != nil {
+            t.Errorf("ReadFile(%s) failed: %s", name, err)
+            continue
+        }
+        configs, problems := parseProjects(js)
+        if len(configs) != 1 {
+            t.Errorf("%s: unexpected result %v", name, configs)
+            continue
+        }
+        if expected == nil {
+            expected, expectedProblems = configs, problems
+            continue
+        }
+        if !reflect.DeepEqual(expected, configs) {
+            t.Errorf("%s: config %+v differs from %+v", name, configs[0], expected[0])
+        }
+        if !reflect.DeepEqual(expectedProblems, problems) {
+            t.Errorf("%s: problems %v differ from %v", name, problems, expectedProblems)
+        }
+    }
+
+    if _, err = ReadFile(filepath.Join(dir, "projects.ini")); err == nil {
+        t.Error("unsupported format should be reported")
+    }
+}
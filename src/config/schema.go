@@ -0,0 +1,722 @@
+package config
+
+import (
+    "files"
+    "fmt"
+    "net"
+    "net/url"
+    "path"
+    "path/filepath"
+    "project"
+    "regexp"
+    "simplejson"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ProjectConfig 配置文件中一个项目的配置（各项的含义见config/projects.json中的注释）
+type ProjectConfig struct {
+    Name         string            `json:"name"`
+    Root         string            `json:"root"`
+    GoWay        string            `json:"go_way"`
+    Deamon       bool              `json:"deamon"`
+    Main         string            `json:"main"`
+    Depends      []string          `json:"depends"`
+    Args         []string          `json:"args"`
+    CustomScript bool              `json:"custom_script"`
+    TestOnChange bool              `json:"test_on_change"`
+    VetOnChange  bool              `json:"vet_on_change"`
+    StopSignal   string            `json:"stop_signal"`
+    StopTimeout  time.Duration     `json:"stop_timeout"`
+    ProxyListen  string            `json:"proxy_listen"`
+    ProxyTarget  string            `json:"proxy_target"`
+    LiveReload   bool              `json:"live_reload"`
+    Env          map[string]string `json:"env"`
+    EnvFile      []string          `json:"env_file"`
+    LogFile      string            `json:"log_file"`
+    LogRotate    *LogRotateConfig  `json:"log_rotate"` // 没有配置时为nil
+    Build        *BuildConfig      `json:"build"`      // 没有配置时为nil
+    Hooks        *HooksConfig      `json:"hooks"`      // 没有配置时为nil
+    Ready        *ReadyConfig      `json:"ready"`      // 没有配置时为nil
+    Watch        *WatchConfig      `json:"watch"`      // 没有配置时为nil
+}
+
+// BuildConfig 编译参数的配置
+type BuildConfig struct {
+    Tags     []string          `json:"tags"`
+    Ldflags  string            `json:"ldflags"`
+    Gcflags  string            `json:"gcflags"`
+    Race     bool              `json:"race"`
+    Trimpath bool              `json:"trimpath"`
+    X        map[string]string `json:"x"`
+    Flags    []string          `json:"flags"`
+}
+
+// HooksConfig 编译、启动前后执行的命令的配置
+type HooksConfig struct {
+    PreBuild  []string `json:"pre_build"`
+    PostBuild []string `json:"post_build"`
+    PreStart  []string `json:"pre_start"`
+    OnFailure []string `json:"on_failure"`
+}
+
+// ReadyConfig 就绪检测的配置
+type ReadyConfig struct {
+    TCP     string        `json:"tcp"`
+    HTTP    string        `json:"http"`
+    Log     string        `json:"log"`
+    Alive   time.Duration `json:"alive"`
+    Timeout time.Duration `json:"timeout"`
+}
+
+// LogRotateConfig 日志文件轮转的配置，没有配置的项为0（使用默认值）
+type LogRotateConfig struct {
+    MaxSize    int64         `json:"max_size"`
+    MaxAge     time.Duration `json:"max_age"`
+    MaxBackups int           `json:"max_backups"`
+}
+
+// WatchConfig 监听规则的配置，没有配置的项为nil（使用默认规则）
+type WatchConfig struct {
+    Include []string `json:"include"`
+    Exclude []string `json:"exclude"`
+}
+
+// sizeRe 匹配大小配置，如10MB、1.5G
+var sizeRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(B|KB?|MB?|GB?)?$`)
+
+// 各级支持的配置项
+var (
+    projectKeys = []string{"name", "root", "go_way", "deamon", "main", "depends", "args", "custom_script", "test_on_change",
+        "vet_on_change", "stop_signal", "stop_timeout", "proxy_listen", "proxy_target", "live_reload", "env", "env_file",
+        "log_file", "log_rotate", "build", "hooks", "ready", "watch"}
+    buildKeys     = []string{"tags", "ldflags", "gcflags", "race", "trimpath", "x", "flags"}
+    hooksKeys     = []string{"pre_build", "post_build", "pre_start", "on_failure"}
+    readyKeys     = []string{"tcp", "http", "log", "alive", "timeout"}
+    logRotateKeys = []string{"max_size", "max_age", "max_backups"}
+    watchKeys     = []string{"include", "exclude"}
+)
+
+// Problem 配置文件中的一个问题
+type Problem struct {
+    Index   int    // 所在的项目是配置中的第几个（从1开始），整个文件的问题为0
+    Project string // 所在项目的名称
+    Key     string // 配置项，如go_way、ready.timeout
+    Msg     string
+    Warning bool // 警告不影响加载；有错误时整个配置文件都不会被加载
+}
+
+func (this Problem) String() string {
+    level := "[ERROR]"
+    if this.Warning {
+        level = "[WARN]"
+    }
+    var where []string
+    if this.Project != "" {
+        where = append(where, "项目"+this.Project)
+    } else if this.Index > 0 {
+        where = append(where, "第"+strconv.Itoa(this.Index)+"个项目")
+    }
+    if this.Key != "" {
+        where = append(where, this.Key)
+    }
+    if len(where) == 0 {
+        return level + " " + this.Msg
+    }
+    return level + " " + strings.Join(where, " ") + "：" + this.Msg
+}
+
+// Problems 配置文件中的所有问题
+type Problems []Problem
+
+// HasError 是否有错误（而不只是警告）
+func (this Problems) HasError() bool {
+    for _, problem := range this {
+        if !problem.Warning {
+            return true
+        }
+    }
+    return false
+}
+
+// Parse 解析并检查配置文件（格式由扩展名决定，见ReadFile）。有错误时返回的配置不完整，不应该使用
+func Parse(configFile string) ([]*ProjectConfig, Problems) {
+    allConfig, err := ReadFile(configFile)
+    if err != nil {
+        return nil, Problems{{Msg: "配置文件格式错误：" + err.Error()}}
+    }
+    return parseProjects(allConfig)
+}
+
+// parseProjects 解析、检查所有项目的配置
+func parseProjects(allConfig *simplejson.Json) ([]*ProjectConfig, Problems) {
+    middleJs, err := allConfig.Array()
+    if err != nil {
+        return nil, Problems{{Msg: "配置文件格式错误：最外层应该是数组（每个元素是一个项目）"}}
+    }
+    var (
+        configs  []*ProjectConfig
+        problems Problems
+        names    = make(map[string]bool)
+    )
+    for i := range middleJs {
+        d := &decoder{js: allConfig.GetIndex(i), index: i + 1, problems: &problems}
+        if _, err := d.js.Map(); err != nil {
+            d.errorf("", "应该是json object")
+            continue
+        }
+        cfg := d.decodeProject()
+        if cfg.Name != "" {
+            if names[cfg.Name] {
+                d.errorf("name", "项目名称重复：%s", cfg.Name)
+            }
+            names[cfg.Name] = true
+        }
+        cfg.validate(d)
+        configs = append(configs, cfg)
+    }
+    return configs, problems
+}
+
+// decoder 读取一个项目的配置，记录类型不对、未知配置项等问题
+type decoder struct {
+    js       *simplejson.Json
+    prefix   string // 嵌套配置项的前缀，如ready.
+    index    int
+    project  string
+    problems *Problems
+}
+
+func (this *decoder) errorf(key, format string, args ...interface{}) {
+    *this.problems = append(*this.problems, Problem{Index: this.index, Project: this.project, Key: this.prefix + key, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (this *decoder) warnf(key, format string, args ...interface{}) {
+    *this.problems = append(*this.problems, Problem{Index: this.index, Project: this.project, Key: this.prefix + key, Msg: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// decodeProject 读取项目的配置
+func (this *decoder) decodeProject() *ProjectConfig {
+    // 先读取名称，之后的问题都以名称标识项目
+    this.project = this.str("name")
+    this.checkKeys(projectKeys)
+    cfg := &ProjectConfig{
+        Name:         this.project,
+        Root:         this.str("root"),
+        GoWay:        this.str("go_way"),
+        Deamon:       this.boolean("deamon", true),
+        Main:         this.str("main"),
+        Depends:      this.strs("depends"),
+        Args:         this.strs("args"),
+        CustomScript: this.boolean("custom_script", false),
+        TestOnChange: this.boolean("test_on_change", false),
+        VetOnChange:  this.boolean("vet_on_change", false),
+        StopSignal:   this.str("stop_signal"),
+        StopTimeout:  this.duration("stop_timeout"),
+        ProxyListen:  this.str("proxy_listen"),
+        ProxyTarget:  this.str("proxy_target"),
+        LiveReload:   this.boolean("live_reload", true),
+        Env:          this.strMap("env"),
+        EnvFile:      this.strs("env_file"),
+        LogFile:      this.str("log_file"),
+    }
+    if rotate := this.object("log_rotate"); rotate != nil {
+        rotate.checkKeys(logRotateKeys)
+        cfg.LogRotate = &LogRotateConfig{
+            MaxSize:    rotate.size("max_size"),
+            MaxAge:     rotate.duration("max_age"),
+            MaxBackups: rotate.count("max_backups"),
+        }
+    }
+    if build := this.object("build"); build != nil {
+        build.checkKeys(buildKeys)
+        cfg.Build = &BuildConfig{
+            Tags:     build.strs("tags"),
+            Ldflags:  build.str("ldflags"),
+            Gcflags:  build.str("gcflags"),
+            Race:     build.boolean("race", false),
+            Trimpath: build.boolean("trimpath", false),
+            X:        build.strMap("x"),
+            Flags:    build.strs("flags"),
+        }
+    }
+    if hooks := this.object("hooks"); hooks != nil {
+        hooks.checkKeys(hooksKeys)
+        cfg.Hooks = &HooksConfig{
+            PreBuild:  hooks.strs("pre_build"),
+            PostBuild: hooks.strs("post_build"),
+            PreStart:  hooks.strs("pre_start"),
+            OnFailure: hooks.strs("on_failure"),
+        }
+    }
+    if ready := this.object("ready"); ready != nil {
+        ready.checkKeys(readyKeys)
+        cfg.Ready = &ReadyConfig{
+            TCP:     ready.str("tcp"),
+            HTTP:    ready.str("http"),
+            Log:     ready.str("log"),
+            Alive:   ready.duration("alive"),
+            Timeout: ready.duration("timeout"),
+        }
+    }
+    if watch := this.object("watch"); watch != nil {
+        watch.checkKeys(watchKeys)
+        cfg.Watch = &WatchConfig{Include: watch.strs("include"), Exclude: watch.strs("exclude")}
+    }
+    return cfg
+}
+
+// checkKeys 未知的配置项给出警告（很可能是拼写错误），并提示最接近的配置项
+func (this *decoder) checkKeys(known []string) {
+    m, _ := this.js.Map()
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        if contains(known, key) {
+            continue
+        }
+        if suggestion := suggest(key, known); suggestion != "" {
+            this.warnf(key, "未知的配置项，是不是%s？", this.prefix+suggestion)
+        } else {
+            this.warnf(key, "未知的配置项，将被忽略")
+        }
+    }
+}
+
+// get 配置项的值，没有配置或者为null时返回false
+func (this *decoder) get(key string) (*simplejson.Json, bool) {
+    js, ok := this.js.CheckGet(key)
+    if !ok || js.IsNull() {
+        return nil, false
+    }
+    return js, true
+}
+
+func (this *decoder) str(key string) string {
+    js, ok := this.get(key)
+    if !ok {
+        return ""
+    }
+    str, err := js.String()
+    if err != nil {
+        this.errorf(key, "应该是字符串")
+    }
+    return str
+}
+
+func (this *decoder) boolean(key string, def bool) bool {
+    js, ok := this.get(key)
+    if !ok {
+        return def
+    }
+    b, err := js.Bool()
+    if err != nil {
+        this.errorf(key, "应该是true或false")
+        return def
+    }
+    return b
+}
+
+// strs 字符串数组，没有配置时返回nil（以便和配置为空数组区分）
+func (this *decoder) strs(key string) []string {
+    js, ok := this.get(key)
+    if !ok {
+        return nil
+    }
+    arr, err := js.Array()
+    if err != nil {
+        this.errorf(key, "应该是字符串数组")
+        return nil
+    }
+    strs := make([]string, 0, len(arr))
+    for i := range arr {
+        str, err := js.GetIndex(i).String()
+        if err != nil {
+            this.errorf(key, "应该是字符串数组（第%d个元素不是字符串）", i+1)
+            continue
+        }
+        strs = append(strs, str)
+    }
+    return strs
+}
+
+// strMap 值为字符串的json object（数字、true/false也转为字符串，方便写PORT: 8080这种配置）
+func (this *decoder) strMap(key string) map[string]string {
+    js, ok := this.get(key)
+    if !ok {
+        return nil
+    }
+    m, err := js.Map()
+    if err != nil {
+        this.errorf(key, "应该是json object（名称: 值）")
+        return nil
+    }
+    strs := make(map[string]string, len(m))
+    for name, value := range m {
+        switch v := value.(type) {
+        case string:
+            strs[name] = v
+        case float64:
+            strs[name] = strconv.FormatFloat(v, 'f', -1, 64)
+        case bool:
+            strs[name] = strconv.FormatBool(v)
+        default:
+            this.errorf(key, "%s的值应该是字符串", name)
+        }
+    }
+    return strs
+}
+
+// duration 时间配置：数字表示秒数，字符串按time.ParseDuration解析（如"500ms"、"10s"）；没有配置为0
+func (this *decoder) duration(key string) time.Duration {
+    js, ok := this.get(key)
+    if !ok {
+        return 0
+    }
+    var d time.Duration
+    if seconds, err := js.Float64(); err == nil {
+        d = time.Duration(seconds * float64(time.Second))
+    } else if str, err := js.String(); err == nil {
+        if d, err = time.ParseDuration(str); err != nil {
+            this.errorf(key, "时间格式错误（应该是秒数，或者\"500ms\"、\"10s\"这种形式）：%s", str)
+            return 0
+        }
+    } else {
+        this.errorf(key, "应该是秒数或者\"500ms\"、\"10s\"这种形式的字符串")
+        return 0
+    }
+    if d < 0 {
+        this.errorf(key, "不能为负数")
+        return 0
+    }
+    return d
+}
+
+// size 大小配置：数字表示MB，字符串如"512KB"、"10MB"、"1GB"（没有单位也表示MB）；没有配置为0
+func (this *decoder) size(key string) int64 {
+    js, ok := this.get(key)
+    if !ok {
+        return 0
+    }
+    if mb, err := js.Float64(); err == nil {
+        if mb < 0 {
+            this.errorf(key, "不能为负数")
+            return 0
+        }
+        return int64(mb * (1 << 20))
+    }
+    str, err := js.String()
+    if err != nil {
+        this.errorf(key, "应该是MB数或者\"512KB\"、\"10MB\"这种形式的字符串")
+        return 0
+    }
+    matches := sizeRe.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(str)))
+    if matches == nil {
+        this.errorf(key, "大小格式错误（应该是MB数或者\"512KB\"、\"10MB\"这种形式）：%s", str)
+        return 0
+    }
+    n, _ := strconv.ParseFloat(matches[1], 64)
+    unit := map[string]float64{"B": 1, "K": 1 << 10, "KB": 1 << 10, "": 1 << 20, "M": 1 << 20, "MB": 1 << 20, "G": 1 << 30, "GB": 1 << 30}[matches[2]]
+    return int64(n * unit)
+}
+
+// count 个数配置：非负整数，没有配置为0
+func (this *decoder) count(key string) int {
+    js, ok := this.get(key)
+    if !ok {
+        return 0
+    }
+    n, err := js.Float64()
+    if err != nil || n < 0 || n != float64(int(n)) {
+        this.errorf(key, "应该是非负整数")
+        return 0
+    }
+    return int(n)
+}
+
+// object 嵌套的配置，没有配置时返回nil
+func (this *decoder) object(key string) *decoder {
+    js, ok := this.get(key)
+    if !ok {
+        return nil
+    }
+    if _, err := js.Map(); err != nil {
+        this.errorf(key, "应该是json object")
+        return nil
+    }
+    return &decoder{js: js, prefix: this.prefix + key + ".", index: this.index, project: this.project, problems: this.problems}
+}
+
+// validate 检查各配置项的值
+func (this *ProjectConfig) validate(d *decoder) {
+    if this.Name == "" {
+        d.errorf("name", "不能为空")
+    }
+    switch this.GoWay {
+    case "", "run", "build", "install", "test":
+    default:
+        msg := fmt.Sprintf("不支持的编译方式\"%s\"，可以是run、build、install或test", this.GoWay)
+        if suggestion := suggest(this.GoWay, []string{"run", "build", "install", "test"}); suggestion != "" {
+            msg += "，是不是" + suggestion + "？"
+        }
+        d.errorf("go_way", "%s", msg)
+    }
+    if this.Root == "" {
+        d.errorf("root", "不能为空")
+    } else if !files.IsDir(this.Root) {
+        d.errorf("root", "目录不存在：%s", this.Root)
+    } else if this.Name != "" {
+        this.validatePaths(d)
+    }
+
+    if this.GoWay == "test" {
+        this.validateTest(d)
+    }
+    if err := project.CheckSignal(this.StopSignal); err != nil {
+        d.errorf("stop_signal", "%s（支持TERM、INT、QUIT、HUP、KILL、USR1、USR2）", err)
+    }
+    if this.ProxyListen != "" {
+        if _, _, err := net.SplitHostPort(this.ProxyListen); err != nil {
+            d.errorf("proxy_listen", "地址格式错误（应该是\":3000\"这种形式）：%s", this.ProxyListen)
+        }
+        if err := project.CheckProxyTarget(this.ProxyTarget); err != nil {
+            d.errorf("proxy_target", "%s", err)
+        }
+    } else if this.ProxyTarget != "" {
+        d.warnf("proxy_target", "没有配置proxy_listen，不会启动代理")
+    }
+    for name := range this.Env {
+        if err := project.CheckEnvName(name); err != nil {
+            d.errorf("env", "%s", err)
+        }
+    }
+    for _, envFile := range this.EnvFile {
+        if !filepath.IsAbs(envFile) {
+            envFile = filepath.Join(this.Root, envFile)
+        }
+        if !files.IsFile(envFile) {
+            d.warnf("env_file", "文件不存在：%s（创建后会自动生效）", envFile)
+        } else if _, err := project.ReadEnvFile(envFile); err != nil {
+            d.errorf("env_file", "%s", err)
+        }
+    }
+    if this.LogFile != "" && this.LogFile != project.LogFileOff {
+        logFile := this.LogFile
+        if !filepath.IsAbs(logFile) {
+            logFile = filepath.Join(this.Root, logFile)
+        }
+        if files.IsDir(logFile) {
+            d.errorf("log_file", "应该是文件，不能是目录：%s", logFile)
+        }
+    } else if this.LogFile == project.LogFileOff && this.LogRotate != nil {
+        d.warnf("log_rotate", "log_file为off时不写日志文件，该配置不起作用")
+    }
+    if this.Build != nil {
+        this.Build.validate(d)
+    }
+    if this.Hooks != nil {
+        this.Hooks.validate(d, this.GoWay == "run" && this.CustomScript)
+    }
+    if this.Ready != nil {
+        if this.Ready.HTTP != "" {
+            if u, err := url.Parse(this.Ready.HTTP); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+                d.errorf("ready.http", "应该是完整的URL，如http://127.0.0.1:8080/health")
+            }
+        }
+        if this.Ready.Log != "" {
+            if _, err := regexp.Compile(this.Ready.Log); err != nil {
+                d.errorf("ready.log", "正则表达式错误：%s", err)
+            }
+        }
+        if !this.Deamon {
+            d.warnf("ready", "只对deamon项目有效")
+        }
+    }
+    if this.Watch != nil {
+        for _, pattern := range append(append([]string(nil), this.Watch.Include...), this.Watch.Exclude...) {
+            if _, err := path.Match(pattern, ""); err != nil {
+                d.errorf("watch", "规则格式错误：%s", pattern)
+            }
+        }
+    }
+}
+
+// validate 检查编译参数
+func (this *BuildConfig) validate(d *decoder) {
+    for _, tag := range this.Tags {
+        if tag == "" || strings.ContainsAny(tag, " ,") {
+            d.errorf("build.tags", "tag不能为空，也不能包含空格、逗号：%q", tag)
+        }
+    }
+    for name := range this.X {
+        if !strings.Contains(name, ".") {
+            d.errorf("build.x", "变量应该是\"包路径.变量名\"的形式，如main.buildTime：%s", name)
+        }
+    }
+    for _, flag := range this.Flags {
+        if flag == "-o" || strings.HasPrefix(flag, "-o=") {
+            d.errorf("build.flags", "不能设置-o，可执行文件由autogo决定")
+        } else if !strings.HasPrefix(flag, "-") {
+            d.warnf("build.flags", "%s不是以-开始，确定是go命令的参数吗？", flag)
+        }
+    }
+}
+
+// validateTest go_way为test时只运行测试，不编译、启动项目，和启动相关的配置不起作用
+func (this *ProjectConfig) validateTest(d *decoder) {
+    keys := []string{"main", "custom_script", "test_on_change", "proxy_listen", "ready"}
+    for i, set := range []bool{this.Main != "", this.CustomScript, this.TestOnChange, this.ProxyListen != "", this.Ready != nil} {
+        if set {
+            d.warnf(keys[i], "go_way为test时只运行测试，该配置不起作用")
+        }
+    }
+}
+
+// validate 检查hook命令，runScript表示自定义脚本go run（编译和运行是一步）
+func (this *HooksConfig) validate(d *decoder, runScript bool) {
+    for i, commands := range [][]string{this.PreBuild, this.PostBuild, this.PreStart, this.OnFailure} {
+        for _, command := range commands {
+            if strings.TrimSpace(command) == "" {
+                d.errorf("hooks."+hooksKeys[i], "命令不能为空")
+            }
+        }
+    }
+    if runScript && len(this.PostBuild) > 0 {
+        d.warnf("hooks.post_build", "go_way为run且custom_script为true时编译和运行是一步，不会执行")
+    }
+}
+
+// validatePaths 按编译方式检查main和depends（规则见config/projects.json中main的注释）
+func (this *ProjectConfig) validatePaths(d *decoder) {
+    if filepath.IsAbs(this.Main) {
+        d.errorf("main", "必须是相对路径：%s", this.Main)
+        return
+    }
+    if files.Exist(filepath.Join(this.Root, "go.mod")) {
+        // Go Module项目：main是main包所在目录（或其中的.go文件），depends是本地模块目录
+        if this.Main != "" {
+            dir := this.Main
+            if strings.HasSuffix(dir, ".go") {
+                dir = filepath.Dir(dir)
+            }
+            if !files.IsDir(filepath.Join(this.Root, dir)) {
+                d.errorf("main", "目录不存在：%s", filepath.Join(this.Root, dir))
+            }
+        }
+        for _, depend := range this.Depends {
+            dir := depend
+            if !filepath.IsAbs(dir) {
+                dir = filepath.Join(this.Root, dir)
+            }
+            if !files.Exist(filepath.Join(dir, "go.mod")) {
+                d.errorf("depends", "依赖的项目%s不是Go Module（没有go.mod）", depend)
+            }
+        }
+        return
+    }
+
+    src := filepath.Join(this.Root, "src")
+    if !files.IsDir(src) {
+        d.errorf("root", "项目没有go.mod，也没有src目录：%s", this.Root)
+        return
+    }
+    switch this.GoWay {
+    case "test":
+        // 只运行测试，不需要main
+    case "run", "build":
+        mainFile := this.Main
+        if mainFile == "" {
+            mainFile = this.Name + ".go"
+        } else if !strings.HasSuffix(mainFile, ".go") {
+            d.errorf("main", "go_way为%s时，应该是main函数所在的.go文件（相对src），如%s/main.go", this.GoWay, this.Name)
+            return
+        }
+        if !files.IsFile(filepath.Join(src, mainFile)) {
+            d.errorf("main", "文件不存在：%s", filepath.Join(src, mainFile))
+        }
+    default:
+        dir := this.Name
+        if this.Main != "" {
+            dir = filepath.Dir(this.Main)
+            if !strings.HasSuffix(this.Main, ".go") || dir == "." {
+                d.errorf("main", "go_way为install时，应该是\"dir/filename.go\"这种形式（相对src），生成的可执行文件名是dir")
+                return
+            }
+        }
+        if !files.IsDir(filepath.Join(src, dir)) {
+            d.errorf("main", "main包目录不存在：%s", filepath.Join(src, dir))
+        }
+    }
+    for _, depend := range this.Depends {
+        // 和编译时一样，相对路径是相对于项目根目录的
+        if !filepath.IsAbs(depend) {
+            depend = filepath.Join(this.Root, depend)
+        }
+        if !files.IsDir(depend) {
+            d.errorf("depends", "目录不存在：%s", depend)
+        }
+    }
+}
+
+func contains(strs []string, str string) bool {
+    for _, s := range strs {
+        if s == str {
+            return true
+        }
+    }
+    return false
+}
+
+// suggest 返回candidates中和word最接近的一个（编辑距离不超过2，且不超过word长度的一半），没有时返回空
+func suggest(word string, candidates []string) string {
+    best, bestDistance := "", 3
+    for _, candidate := range candidates {
+        distance := editDistance(strings.ToLower(word), candidate)
+        if distance < bestDistance && distance*2 <= len(word) {
+            best, bestDistance = candidate, distance
+        }
+    }
+    return best
+}
+
+// editDistance 编辑距离（相邻字符交换算一次编辑，如daemon和deamon的距离是1）
+func editDistance(a, b string) int {
+    ra, rb := []rune(a), []rune(b)
+    // d[i][j]是ra[:i]和rb[:j]的编辑距离
+    d := make([][]int, len(ra)+1)
+    for i := range d {
+        d[i] = make([]int, len(rb)+1)
+        d[i][0] = i
+    }
+    for j := range d[0] {
+        d[0][j] = j
+    }
+    for i := 1; i <= len(ra); i++ {
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i-1] == rb[j-1] {
+                cost = 0
+            }
+            d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+            if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+                d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+            }
+        }
+    }
+    return d[len(ra)][len(rb)]
+}
+
+func minInt(values ...int) int {
+    m := values[0]
+    for _, v := range values[1:] {
+        if v < m {
+            m = v
+        }
+    }
+    return m
+}
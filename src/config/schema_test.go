@@ -0,0 +1,103 @@
+package config
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "simplejson"
+    "strings"
+    "testing"
+    "time"
+)
+
+// parseString 解析配置内容，返回配置以及所有问题（每个问题一行）
+func parseString(t *testing.T, content string) ([]*ProjectConfig, Problems, string) {
+    js, err := simplejson.NewJsonc([]byte(content))
+    if err != nil {
+        t.Fatalf("NewJsonc failed: %s", err)
+    }
+    configs, problems := parseProjects(js)
+    var lines []string
+    for _, problem := range problems {
+        lines = append(lines, problem.String())
+    }
+    return configs, problems, strings.Join(lines, "\n")
+}
+
+func TestParseProjects(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+    root := filepath.Join(dir, "web")
+    if err = os.MkdirAll(filepath.Join(root, "src", "web"), 0777); err != nil {
+        t.Fatalf("Failed to create project: %s", err)
+    }
+    ioutil.WriteFile(filepath.Join(root, "src", "web", "main.go"), []byte("package main\n"), 0666)
+    root = filepath.ToSlash(root)
+
+    configs, problems, output := parseString(t, `[{
+        "name": "web", "root": "`+root+`", "go_way": "build", "main": "web/main.go",
+        "stop_timeout": "2s", "ready": {"tcp": ":8080", "timeout": 10}, "watch": {"exclude": []},
+        "log_rotate": {"max_size": "512KB", "max_age": "24h", "max_backups": 3},
+    }]`)
+    if problems.HasError() || len(problems) > 0 {
+        t.Fatalf("unexpected problems:\n%s", output)
+    }
+    cfg := configs[0]
+    if cfg.Name != "web" || !cfg.Deamon || !cfg.LiveReload || cfg.StopTimeout != 2*time.Second {
+        t.Errorf("unexpected config: %+v", cfg)
+    }
+    if cfg.Ready == nil || cfg.Ready.TCP != ":8080" || cfg.Ready.Timeout != 10*time.Second {
+        t.Errorf("unexpected ready config: %+v", cfg.Ready)
+    }
+    if cfg.Watch == nil || cfg.Watch.Include != nil || cfg.Watch.Exclude == nil {
+        t.Errorf("unexpected watch config: %+v", cfg.Watch)
+    }
+    if cfg.LogRotate == nil || cfg.LogRotate.MaxSize != 512<<10 || cfg.LogRotate.MaxAge != 24*time.Hour || cfg.LogRotate.MaxBackups != 3 {
+        t.Errorf("unexpected log_rotate config: %+v", cfg.LogRotate)
+    }
+
+    _, problems, output = parseString(t, `[
+        {"name": "web", "root": "`+root+`", "go_way": "buidl", "daemon": false, "ready": {"timout": 1}},
+        {"root": "`+root+`", "deamon": "yes", "stop_signal": "FOO"},
+        {"name": "web", "root": "`+root+`", "go_way": "install", "main": "main.go"},
+        {"name": "api", "root": "`+root+`", "go_way": "run", "log_rotate": {"max_size": "10XB", "max_backups": 1.5}}
+    ]`)
+    if !problems.HasError() {
+        t.Error("problems should contain errors")
+    }
+    for _, expected := range []string{
+        "[WARN] 项目web daemon：未知的配置项，是不是deamon？",
+        "[WARN] 项目web ready.timout：未知的配置项，是不是ready.timeout？",
+        "[ERROR] 项目web go_way：不支持的编译方式\"buidl\"，可以是run、build、install或test，是不是build？",
+        "[ERROR] 第2个项目 name：不能为空",
+        "[ERROR] 第2个项目 deamon：应该是true或false",
+        "[ERROR] 第2个项目 stop_signal：",
+        "[ERROR] 项目web name：项目名称重复：web",
+        "[ERROR] 项目web main：go_way为install时，应该是\"dir/filename.go\"这种形式",
+        "[ERROR] 项目api main：文件不存在：",
+        "[ERROR] 项目api log_rotate.max_size：大小格式错误",
+        "[ERROR] 项目api log_rotate.max_backups：应该是非负整数",
+    } {
+        if !strings.Contains(output, expected) {
+            t.Errorf("problems should contain %q, got:\n%s", expected, output)
+        }
+    }
+}
+
+func TestSuggest(t *testing.T) {
+    cases := map[string]string{
+        "daemon":     "deamon",
+        "go-way":     "go_way",
+        "dependency": "",
+        "x":          "",
+        "Main":       "main",
+    }
+    for word, expected := range cases {
+        if suggestion := suggest(word, projectKeys); suggestion != expected {
+            t.Errorf("suggest(%q) = %q, expected %q", word, suggestion, expected)
+        }
+    }
+}
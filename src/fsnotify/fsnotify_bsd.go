@@ -13,6 +13,7 @@ import (
     "io/ioutil"
     "os"
     "path/filepath"
+    "sync"
     "syscall"
 )
 
@@ -40,6 +41,7 @@ type Watcher struct {
     kq            int                 // File descriptor (as returned by the kqueue() syscall)
     watches       map[string]int      // Map of watched file diescriptors (key: path)
     fsnFlags      map[string]uint32   // Map of watched files to flags used for filter
+    fsnmut        sync.Mutex          // Protects access to fsnFlags.
     enFlags       map[string]uint32   // Map of watched files to evfilt note flags used in kqueue
     paths         map[int]string      // Map of watched paths (key: watch descriptor)
     finfo         map[int]os.FileInfo // Map of file information (isDir, isReg; key: watch descriptor)
@@ -315,7 +317,9 @@ func (w *Watcher) watchDirectoryFiles(dirPath string) error {
         if fileInfo.IsDir() == false {
             // Watch file to mimic linux fsnotify
             e := w.addWatch(filePath, NOTE_DELETE|NOTE_WRITE|NOTE_RENAME)
+            w.fsnmut.Lock()
             w.fsnFlags[filePath] = FSN_ALL
+            w.fsnmut.Unlock()
             if e != nil {
                 return e
             }
@@ -330,7 +334,9 @@ func (w *Watcher) watchDirectoryFiles(dirPath string) error {
 
             // Linux gives deletes if not explicitly watching
             e := w.addWatch(filePath, newFlags)
+            w.fsnmut.Lock()
             w.fsnFlags[filePath] = FSN_ALL
+            w.fsnmut.Unlock()
             if e != nil {
                 return e
             }
@@ -357,7 +363,9 @@ func (w *Watcher) sendDirectoryChangeEvents(dirPath string) {
         filePath := filepath.Join(dirPath, fileInfo.Name())
         _, doesExist := w.fileExists[filePath]
         if doesExist == false {
+            w.fsnmut.Lock()
             w.fsnFlags[filePath] = FSN_ALL
+            w.fsnmut.Unlock()
             // Send create event
             fileEvent := new(FileEvent)
             fileEvent.Name = filePath
@@ -2,6 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+//go:build linux
 // +build linux
 
 // Package fsnotify implements a wrapper for the Linux inotify system.
@@ -53,6 +54,7 @@ type Watcher struct {
     fd            int               // File descriptor (as returned by the inotify_init() syscall)
     watches       map[string]*watch // Map of inotify watches (key: path)
     fsnFlags      map[string]uint32 // Map of watched files to flags used for filter
+    fsnmut        sync.Mutex        // Protects access to fsnFlags.
     paths         map[int]string    // Map of watched paths (key: watch descriptor)
     Error         chan error        // Errors are sent on this channel
     internalEvent chan *FileEvent   // Events are queued on this channel
@@ -92,14 +94,21 @@ func (w *Watcher) Close() error {
     }
     w.isClosed = true
 
+    // Send "quit" message to the reader goroutine before removing the watches:
+    // removing them wakes up the blocking read (IN_IGNORED), which then sees it
+    w.done <- true
+
     // Remove all watches
+    w.mu.Lock()
+    paths := make([]string, 0, len(w.watches))
     for path := range w.watches {
+        paths = append(paths, path)
+    }
+    w.mu.Unlock()
+    for _, path := range paths {
         w.RemoveWatch(path)
     }
 
-    // Send "quit" message to the reader goroutine
-    w.done <- true
-
     return nil
 }
 
@@ -110,11 +119,13 @@ func (w *Watcher) addWatch(path string, flags uint32) error {
         return errors.New("inotify instance already closed")
     }
 
+    w.mu.Lock()
     watchEntry, found := w.watches[path]
     if found {
         watchEntry.flags |= flags
         flags |= syscall.IN_MASK_ADD
     }
+    w.mu.Unlock()
     wd, errno := syscall.InotifyAddWatch(w.fd, path, flags)
     if wd == -1 {
         return errno
@@ -135,15 +146,19 @@ func (w *Watcher) watch(path string) error {
 
 // RemoveWatch removes path from the watched file set.
 func (w *Watcher) removeWatch(path string) error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
     watch, ok := w.watches[path]
     if !ok {
         return errors.New(fmt.Sprintf("can't remove non-existent inotify watch for: %s", path))
     }
+    delete(w.watches, path)
+    delete(w.paths, int(watch.wd))
     success, errno := syscall.InotifyRmWatch(w.fd, watch.wd)
-    if success == -1 {
+    // EINVAL: the kernel already removed the watch (e.g. the path was deleted)
+    if success == -1 && errno != syscall.EINVAL {
         return os.NewSyscallError("inotify_rm_watch", errno)
     }
-    delete(w.watches, path)
     return nil
 }
 
@@ -208,15 +223,26 @@ func (w *Watcher) readEvents() {
             }
 
             // Setup FSNotify flags (inherit from directory watch)
+            w.fsnmut.Lock()
             fsnFlags := w.fsnFlags[watchedName]
             _, fsnFound := w.fsnFlags[event.Name]
             if !fsnFound {
                 w.fsnFlags[event.Name] = fsnFlags
             }
+            w.fsnmut.Unlock()
 
             // Send the events that are not ignored on the events channel
             if (event.mask & IN_IGNORED) == 0 {
                 w.internalEvent <- event
+            } else {
+                // The watch was removed (explicitly or because the path was deleted
+                // or unmounted), forget it so it doesn't dangle in watches/paths
+                w.mu.Lock()
+                if watch, ok := w.watches[watchedName]; ok && watch.wd == uint32(raw.Wd) {
+                    delete(w.watches, watchedName)
+                }
+                delete(w.paths, int(raw.Wd))
+                w.mu.Unlock()
             }
 
             // Move to the next event in the buffer
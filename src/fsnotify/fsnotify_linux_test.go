@@ -0,0 +1,76 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsnotify
+
+import (
+    "os"
+    "testing"
+    "time"
+)
+
+func TestFsnotifyDeletedDirWatchRemoved(t *testing.T) {
+    // Create an fsnotify watcher instance and initialize it
+    watcher, err := NewWatcher()
+    if err != nil {
+        t.Fatalf("NewWatcher() failed: %s", err)
+    }
+
+    const testDir string = "_test"
+    const testSubDir string = "_test/sub"
+
+    // Create directories to watch
+    if err = os.MkdirAll(testSubDir, 0777); err != nil {
+        t.Fatalf("Failed to create test directory: %s", err)
+    }
+    defer os.RemoveAll(testDir)
+
+    if err = watcher.Watch(testDir); err != nil {
+        t.Fatalf("Watcher.Watch() failed: %s", err)
+    }
+    if err = watcher.Watch(testSubDir); err != nil {
+        t.Fatalf("Watcher.Watch() failed: %s", err)
+    }
+
+    // Receive events on the event channel on a separate goroutine
+    eventstream := watcher.Event
+    done := make(chan bool)
+    go func() {
+        for event := range eventstream {
+            t.Logf("event received: %s", event)
+        }
+        done <- true
+    }()
+
+    // Deleting the directory makes the kernel drop its watch (IN_IGNORED)
+    if err = os.Remove(testSubDir); err != nil {
+        t.Fatalf("Failed to remove test sub directory: %s", err)
+    }
+
+    removed := false
+    for i := 0; i < 50 && !removed; i++ {
+        time.Sleep(20 * time.Millisecond)
+        watcher.mu.Lock()
+        _, found := watcher.watches[testSubDir]
+        watcher.mu.Unlock()
+        removed = !found
+    }
+    if !removed {
+        t.Fatal("watch of the deleted directory was not removed")
+    }
+
+    // The watch is gone, so removing it explicitly reports that
+    if err = watcher.RemoveWatch(testSubDir); err == nil {
+        t.Fatal("RemoveWatch() of a forgotten watch should report it doesn't exist")
+    }
+
+    t.Log("calling Close()")
+    watcher.Close()
+    select {
+    case <-done:
+        t.Log("event channel closed")
+    case <-time.After(2 * time.Second):
+        t.Fatal("event stream was not closed after 2 seconds")
+    }
+}
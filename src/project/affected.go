@@ -0,0 +1,119 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+// Affected 一批文件改动影响到的包（通过go list计算）
+type Affected struct {
+    Packages []*Package      // 项目中所有的包（go list ./...）
+    Changed  map[string]bool // 改动的文件所在的包（import path）
+    All      bool            // 有不能对应到某个包的改动（非Go文件、目录、环境变量文件等），认为所有包都受影响
+}
+
+// Affected 计算改动的文件（绝对路径）对应的包；files为空时认为所有包都受影响
+func (this *Project) Affected(files ...string) (*Affected, error) {
+    if err := this.writeModFile(); err != nil {
+        return nil, err
+    }
+    packages, err := DefaultBuilder.ListPackages(this, false, "./...")
+    if err != nil {
+        return nil, err
+    }
+    affected := &Affected{Packages: packages, Changed: make(map[string]bool), All: len(files) == 0}
+    dirs := make(map[string]string, len(packages))
+    for _, pkg := range packages {
+        dirs[pkg.Dir] = pkg.ImportPath
+    }
+    for _, name := range files {
+        path, ok := dirs[filepath.Dir(name)]
+        if !ok || !strings.HasSuffix(name, ".go") {
+            affected.All = true
+            continue
+        }
+        affected.Changed[path] = true
+    }
+    return affected, nil
+}
+
+// ChangedPackages 改动的包（import path，已排序），All为true时是所有的包
+func (this *Affected) ChangedPackages() []string {
+    var paths []string
+    for _, pkg := range this.Packages {
+        if this.All || this.Changed[pkg.ImportPath] {
+            paths = append(paths, pkg.ImportPath)
+        }
+    }
+    sort.Strings(paths)
+    return paths
+}
+
+// TestPackages 需要测试的包（import path）：改动的包，以及它本身或者它的测试（直接间接）依赖改动的包的包，
+// 只包括有测试文件的包
+func (this *Affected) TestPackages() []string {
+    var paths []string
+    for _, pkg := range this.Packages {
+        if len(pkg.TestGoFiles)+len(pkg.XTestGoFiles) == 0 {
+            continue
+        }
+        if this.All || this.Changed[pkg.ImportPath] || pkg.imports(this.Changed) {
+            paths = append(paths, pkg.ImportPath)
+        }
+    }
+    return paths
+}
+
+// imports 包或者它的测试是否（直接或间接）导入了packages中的某个包
+func (this *Package) imports(packages map[string]bool) bool {
+    for _, imports := range [][]string{this.Deps, this.TestImports, this.XTestImports} {
+        for _, path := range imports {
+            if packages[path] {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// affectsMain 改动是否影响项目的main包（main包或者它依赖的项目中的包有改动），不影响时不需要重新编译。
+// 自定义脚本编译的项目、无法确定时都认为有影响
+func (this *Project) affectsMain(affected *Affected) bool {
+    if affected.All || this.CustomScript || this.GoWay == "test" {
+        return true
+    }
+    changedDirs := make(map[string]bool)
+    for _, pkg := range affected.Packages {
+        if affected.Changed[pkg.ImportPath] {
+            changedDirs[pkg.Dir] = true
+        }
+    }
+    deps, err := DefaultBuilder.ListPackages(this, true, this.mainPackage())
+    if err != nil {
+        return true
+    }
+    for _, pkg := range deps {
+        if changedDirs[pkg.Dir] {
+            return true
+        }
+    }
+    return false
+}
+
+// mainPackage go list中表示main包的参数（相对于监听目录）：GOPATH项目中run、build是main函数所在文件，
+// install是main包的import path；Go Module项目是main包所在目录
+func (this *Project) mainPackage() string {
+    if this.module || this.GoWay != "run" && this.GoWay != "build" {
+        return this.MainFile
+    }
+    rel, err := filepath.Rel(this.srcAbsolutePath, filepath.Join(this.Root, this.MainFile))
+    if err != nil {
+        return this.MainFile
+    }
+    return "./" + filepath.ToSlash(rel)
+}
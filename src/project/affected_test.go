@@ -0,0 +1,60 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestAffected(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    // app依赖lib，tools/gen是独立的工具
+    src := filepath.Join(dir, "src")
+    writeTestFile(t, filepath.Join(src, "app", "main.go"), "package main\n\nimport \"lib\"\n\nfunc main() { lib.Hello() }\n")
+    writeTestFile(t, filepath.Join(src, "lib", "lib.go"), "package lib\n\nfunc Hello() {}\n")
+    writeTestFile(t, filepath.Join(src, "lib", "lib_test.go"), "package lib\n\nimport \"testing\"\n\nfunc TestHello(t *testing.T) { Hello() }\n")
+    writeTestFile(t, filepath.Join(src, "tools", "gen", "gen.go"), "package main\n\nfunc main() {}\n")
+    prj, err := New("app", dir, "build", "app/main.go", true)
+    if err != nil {
+        t.Fatalf("New failed: %s", err)
+    }
+
+    affected, err := prj.Affected(filepath.Join(src, "lib", "lib.go"))
+    if err != nil {
+        t.Fatalf("Affected failed: %s", err)
+    }
+    if affected.All || !reflect.DeepEqual(affected.ChangedPackages(), []string{"lib"}) {
+        t.Errorf("unexpected changed packages: %v", affected.ChangedPackages())
+    }
+    if !reflect.DeepEqual(affected.TestPackages(), []string{"lib"}) {
+        t.Errorf("unexpected test packages: %v", affected.TestPackages())
+    }
+    if !prj.affectsMain(affected) {
+        t.Error("lib should affect the main package")
+    }
+
+    if affected, err = prj.Affected(filepath.Join(src, "tools", "gen", "gen.go")); err != nil {
+        t.Fatalf("Affected failed: %s", err)
+    }
+    if prj.affectsMain(affected) || len(affected.TestPackages()) != 0 {
+        t.Errorf("tools/gen should not affect the main package: %v", affected.ChangedPackages())
+    }
+
+    if affected, err = prj.Affected(filepath.Join(src, "app", "index.html")); err != nil {
+        t.Fatalf("Affected failed: %s", err)
+    }
+    if !affected.All || !prj.affectsMain(affected) {
+        t.Error("non-Go files should affect all packages")
+    }
+}
@@ -0,0 +1,211 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "io"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// DefaultBuilder 项目默认使用的Builder
+var DefaultBuilder = &Builder{GoCmd: "go"}
+
+// Builder 直接通过os/exec调用go工具链编译项目，不需要在项目中生成install.sh/install.bat；
+// 只有项目配置了custom_script时，才使用templates中的模板生成脚本编译
+type Builder struct {
+    GoCmd string   // go命令（可以是绝对路径），默认是go
+    Env   []string // 额外的环境变量，形如KEY=value，会覆盖计算出的同名环境变量
+}
+
+// BuildResult 一次编译（或脚本执行）的结果
+type BuildResult struct {
+    ExitCode int           // 退出码，0表示成功
+    Stdout   string        // 标准输出
+    Stderr   string        // 标准错误输出
+    Duration time.Duration // 耗时
+}
+
+// Package go list -json输出的包信息（只包括用到的字段）
+type Package struct {
+    Dir          string   // 包所在目录
+    ImportPath   string   // 包的import path
+    Deps         []string // 直接和间接依赖的包
+    TestGoFiles  []string // 包内测试文件
+    XTestGoFiles []string // 外部测试（package xxx_test）文件
+    TestImports  []string // 包内测试导入的包
+    XTestImports []string // 外部测试导入的包
+}
+
+// Success 编译是否成功
+func (this *BuildResult) Success() bool {
+    return this.ExitCode == 0
+}
+
+// Output 合并标准输出和标准错误输出（去掉首尾空白）
+func (this *BuildResult) Output() string {
+    return strings.TrimSpace(strings.TrimSpace(this.Stdout) + "\n" + strings.TrimSpace(this.Stderr))
+}
+
+// Build 编译项目，等待编译结束。只有go命令无法执行时才返回error，编译失败通过BuildResult体现
+func (this *Builder) Build(prj *Project) (*BuildResult, error) {
+    if prj.CustomScript {
+        return this.run(prj.scriptCommand())
+    }
+    return this.run(this.Command(prj))
+}
+
+// Command 返回编译项目的命令（go build/install），并设置好工作目录和环境变量。
+// go_way为run时同样是go build（-o到临时目录，见Project.Run），编译通过后再运行
+func (this *Builder) Command(prj *Project) *exec.Cmd {
+    goWay := prj.GoWay
+    switch goWay {
+    case "build":
+    case "run":
+        goWay = "build"
+    default:
+        goWay = "install"
+    }
+    args := append([]string{goWay}, prj.Options...)
+    args = append(args, prj.buildArgs()...)
+    args = append(args, prj.MainFile)
+    cmd := exec.Command(this.goCmd(), args...)
+    cmd.Dir = prj.Root
+    cmd.Env = this.Environ(prj)
+    return cmd
+}
+
+// TestCommand 返回测试项目中某些包的命令（go test -json -cover），编译参数（见BuildFlags）同样有效
+func (this *Builder) TestCommand(prj *Project, packages []string) *exec.Cmd {
+    args := []string{"test", "-json", "-cover"}
+    if prj.module && len(prj.Depends) > 0 {
+        args = append(args, "-modfile="+prj.modFilePath())
+    }
+    args = append(args, prj.buildArgs()...)
+    cmd := exec.Command(this.goCmd(), append(args, packages...)...)
+    cmd.Dir = prj.srcAbsolutePath
+    cmd.Env = this.Environ(prj)
+    return cmd
+}
+
+// VetCommand 返回对项目中某些包执行go vet的命令
+func (this *Builder) VetCommand(prj *Project, packages []string) *exec.Cmd {
+    args := []string{"vet"}
+    if prj.module && len(prj.Depends) > 0 {
+        args = append(args, "-modfile="+prj.modFilePath())
+    }
+    args = append(args, prj.buildTags()...)
+    cmd := exec.Command(this.goCmd(), append(args, packages...)...)
+    cmd.Dir = prj.srcAbsolutePath
+    cmd.Env = this.Environ(prj)
+    return cmd
+}
+
+// ListPackages 在项目的监听目录中执行go list -e -json列出包，deps为true时还列出它们（直接间接）依赖的包（-deps）
+func (this *Builder) ListPackages(prj *Project, deps bool, patterns ...string) ([]*Package, error) {
+    args := []string{"list", "-e", "-json"}
+    if deps {
+        args = append(args, "-deps")
+    }
+    if prj.module && len(prj.Depends) > 0 {
+        args = append(args, "-modfile="+prj.modFilePath())
+    }
+    args = append(args, prj.buildTags()...)
+    cmd := exec.Command(this.goCmd(), append(args, patterns...)...)
+    cmd.Dir = prj.srcAbsolutePath
+    cmd.Env = this.Environ(prj)
+    result, err := this.run(cmd)
+    if err != nil {
+        return nil, err
+    }
+    if !result.Success() {
+        return nil, errors.New(result.Output())
+    }
+    var packages []*Package
+    decoder := json.NewDecoder(strings.NewReader(result.Stdout))
+    for {
+        pkg := new(Package)
+        if err = decoder.Decode(pkg); err == io.EOF {
+            return packages, nil
+        } else if err != nil {
+            return nil, err
+        }
+        packages = append(packages, pkg)
+    }
+}
+
+// goCmd go命令，默认是go
+func (this *Builder) goCmd() string {
+    if this.GoCmd == "" {
+        return "go"
+    }
+    return this.GoCmd
+}
+
+// Environ 计算编译项目时的环境变量：在项目环境变量（见Project.Environ）的基础上，
+// GOPATH项目设置GOPATH为项目根目录和depends，Go Module项目打开GO111MODULE，并将GOBIN设置为项目的bin目录
+func (this *Builder) Environ(prj *Project) []string {
+    var env []string
+    if prj.module {
+        env = []string{"GO111MODULE=on", "GOBIN=" + prj.binAbsolutePath}
+    } else {
+        gopath := []string{prj.Root}
+        for _, depend := range prj.Depends {
+            if !filepath.IsAbs(depend) {
+                depend = filepath.Join(prj.Root, depend)
+            }
+            gopath = append(gopath, depend)
+        }
+        env = []string{"GO111MODULE=off", "GOPATH=" + strings.Join(gopath, string(os.PathListSeparator))}
+    }
+    return mergeEnv(prj.Environ(), append(env, this.Env...))
+}
+
+// run 执行命令，等待其结束，收集输出和退出码
+func (this *Builder) run(cmd *exec.Cmd) (*BuildResult, error) {
+    var stdout, stderr bytes.Buffer
+    cmd.Stdout = &stdout
+    cmd.Stderr = &stderr
+    start := time.Now()
+    err := cmd.Run()
+    result := &BuildResult{
+        Stdout:   stdout.String(),
+        Stderr:   stderr.String(),
+        Duration: time.Since(start),
+    }
+    if err != nil {
+        exitErr, ok := err.(*exec.ExitError)
+        if !ok {
+            return nil, err
+        }
+        result.ExitCode = exitErr.ExitCode()
+    }
+    return result, nil
+}
+
+// mergeEnv 将overrides合并到env中，同名的变量以overrides为准
+func mergeEnv(env, overrides []string) []string {
+    index := make(map[string]int, len(env))
+    merged := make([]string, 0, len(env)+len(overrides))
+    for _, kv := range append(env, overrides...) {
+        key := kv
+        if i := strings.Index(kv, "="); i >= 0 {
+            key = kv[:i]
+        }
+        if i, ok := index[key]; ok {
+            merged[i] = kv
+            continue
+        }
+        index[key] = len(merged)
+        merged = append(merged, kv)
+    }
+    return merged
+}
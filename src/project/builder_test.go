@@ -0,0 +1,150 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "testing"
+)
+
+// envValue 环境变量列表中name的值
+func envValue(env []string, name string) string {
+    for _, kv := range env {
+        if strings.HasPrefix(kv, name+"=") {
+            return kv[len(name)+1:]
+        }
+    }
+    return ""
+}
+
+func TestBuild(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    tests := []struct {
+        name           string
+        module, broken bool
+    }{
+        {"gopath", false, false},
+        {"gopathbroken", false, true},
+        {"module", true, false},
+        {"modulebroken", true, true},
+    }
+    for _, test := range tests {
+        prj := newTestProject(t, dir, test.name, test.module, test.broken)
+        result, err := DefaultBuilder.Build(prj)
+        if err != nil {
+            t.Fatalf("%s: Build failed: %s", test.name, err)
+        }
+        if result.Duration <= 0 {
+            t.Errorf("%s: Duration is %s", test.name, result.Duration)
+        }
+        if test.broken {
+            if result.Success() || result.ExitCode == 0 {
+                t.Errorf("%s: broken project was built, exit code %d", test.name, result.ExitCode)
+            }
+            // 编译错误在标准错误输出中
+            if !strings.Contains(result.Stderr, "fmt.Prnt") || strings.Contains(result.Stdout, "fmt.Prnt") {
+                t.Errorf("%s: unexpected output, stdout %q, stderr %q", test.name, result.Stdout, result.Stderr)
+            }
+            continue
+        }
+        if !result.Success() || result.ExitCode != 0 {
+            t.Errorf("%s: Build returned exit code %d: %s", test.name, result.ExitCode, result.Output())
+        }
+        if _, err = os.Stat(prj.getExeFilePath()); err != nil {
+            t.Errorf("%s: executable was not built: %s", test.name, err)
+        }
+    }
+}
+
+func TestBuilderRun(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("shell command is sh only")
+    }
+    result, err := DefaultBuilder.run(shellCommand("echo out; echo err >&2; exit 3"))
+    if err != nil {
+        t.Fatalf("run failed: %s", err)
+    }
+    if result.ExitCode != 3 || result.Success() || result.Stdout != "out\n" || result.Stderr != "err\n" {
+        t.Errorf("run returned %+v", result)
+    }
+    if result.Output() != "out\nerr" {
+        t.Errorf("Output() returned %q", result.Output())
+    }
+
+    // 命令无法执行时返回error，而不是编译失败
+    builder := &Builder{GoCmd: filepath.Join(os.TempDir(), "autogo-no-such-go")}
+    if _, err = builder.run(builder.Command(&Project{GoWay: "build"})); err == nil {
+        t.Error("run of a missing command should fail")
+    }
+}
+
+func TestBuilderEnviron(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    lib := filepath.Join(dir, "lib")
+    writeTestFile(t, filepath.Join(lib, "go.mod"), "module example.com/lib\n\ngo 1.16\n")
+    gopath := newTestProject(t, dir, "gopath", false, false)
+    gopath.Depends = []string{"../lib", lib}
+    module := newTestProject(t, dir, "module", true, false)
+
+    builder := &Builder{Env: []string{"CGO_ENABLED=0"}}
+    env := builder.Environ(gopath)
+    want := strings.Join([]string{gopath.Root, lib, lib}, string(os.PathListSeparator))
+    if envValue(env, "GO111MODULE") != "off" || envValue(env, "GOPATH") != want {
+        t.Errorf("GOPATH project: GO111MODULE=%s GOPATH=%s, want GOPATH=%s",
+            envValue(env, "GO111MODULE"), envValue(env, "GOPATH"), want)
+    }
+    if envValue(env, "CGO_ENABLED") != "0" {
+        t.Error("Builder.Env was not applied")
+    }
+
+    env = builder.Environ(module)
+    if envValue(env, "GO111MODULE") != "on" || envValue(env, "GOBIN") != module.binAbsolutePath {
+        t.Errorf("module project: GO111MODULE=%s GOBIN=%s", envValue(env, "GO111MODULE"), envValue(env, "GOBIN"))
+    }
+}
+
+func TestBuildCustomScript(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("custom script is install.sh only")
+    }
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    prj := newTestProject(t, dir, "script", false, false)
+    writeTestFile(t, filepath.Join(prj.Root, installFileName), "#!/bin/sh\necho custom script\necho "+successFlag+"\n")
+
+    // 没有配置custom_script时不使用项目中的脚本
+    cmd := DefaultBuilder.Command(prj)
+    if cmd.Args[0] != "go" || cmd.Args[1] != "build" {
+        t.Errorf("Command returned %v", cmd.Args)
+    }
+    result, err := DefaultBuilder.Build(prj)
+    if err != nil || !result.Success() || strings.Contains(result.Stdout, "custom script") {
+        t.Errorf("Build without custom_script returned %+v %v", result, err)
+    }
+
+    prj.CustomScript = true
+    result, err = DefaultBuilder.Build(prj)
+    if err != nil || !result.Success() || !strings.Contains(result.Stdout, "custom script") {
+        t.Errorf("Build with custom_script returned %+v %v", result, err)
+    }
+}
@@ -0,0 +1,118 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "os/exec"
+    "sort"
+    "strings"
+    "time"
+)
+
+// BuildFlags 编译项目时传给go命令的参数
+type BuildFlags struct {
+    Tags     []string
+    Ldflags  string
+    Gcflags  string
+    Race     bool
+    Trimpath bool
+    // X 通过-ldflags "-X 变量=值"注入的变量（如main.buildTime），值中除了可以使用${ROOT}、${NAME}和环境变量（见SetArgs），
+    // 还可以使用${BUILD_TIME}（编译时间）、${GIT_REVISION}（项目当前的git提交）
+    X     map[string]string
+    Flags []string // 其他参数，原样传给go命令
+}
+
+// SetBuildFlags 设置编译参数，nil表示没有额外的参数
+func (this *Project) SetBuildFlags(flags *BuildFlags) {
+    this.buildFlags = flags
+}
+
+// buildArgs 根据BuildFlags生成go命令的参数，每次编译时重新计算（编译时间、git提交会变）
+func (this *Project) buildArgs() []string {
+    flags := this.buildFlags
+    if flags == nil {
+        return nil
+    }
+    args := this.buildTags()
+    if flags.Race {
+        args = append(args, "-race")
+    }
+    if flags.Trimpath {
+        args = append(args, "-trimpath")
+    }
+    if flags.Gcflags != "" {
+        args = append(args, "-gcflags", flags.Gcflags)
+    }
+    if ldflags := this.ldflags(); ldflags != "" {
+        args = append(args, "-ldflags", ldflags)
+    }
+    return append(args, flags.Flags...)
+}
+
+// buildTags -tags参数（go list、go vet也需要，以便选择同样的文件），没有设置时为nil
+func (this *Project) buildTags() []string {
+    if this.buildFlags == nil || len(this.buildFlags.Tags) == 0 {
+        return nil
+    }
+    return []string{"-tags", strings.Join(this.buildFlags.Tags, ",")}
+}
+
+// ldflags Ldflags加上-X注入的变量
+func (this *Project) ldflags() string {
+    flags := this.buildFlags
+    if len(flags.X) == 0 {
+        return flags.Ldflags
+    }
+    vars := this.vars()
+    vars["BUILD_TIME"] = time.Now().Format(time.RFC3339)
+    for _, value := range flags.X {
+        if strings.Contains(value, "GIT_REVISION") {
+            vars["GIT_REVISION"] = this.gitRevision()
+            break
+        }
+    }
+
+    names := make([]string, 0, len(flags.X))
+    for name := range flags.X {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    var ldflags []string
+    if flags.Ldflags != "" {
+        ldflags = append(ldflags, flags.Ldflags)
+    }
+    for _, name := range names {
+        ldflags = append(ldflags, "-X", quoteFlag(name+"="+expand(flags.X[name], vars)))
+    }
+    return strings.Join(ldflags, " ")
+}
+
+// gitRevision 项目当前的git提交（短hash），有未提交的改动时加上-dirty；不是git仓库时返回unknown
+func (this *Project) gitRevision() string {
+    cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+    cmd.Dir = this.Root
+    output, err := cmd.Output()
+    if err != nil {
+        return "unknown"
+    }
+    revision := strings.TrimSpace(string(output))
+    cmd = exec.Command("git", "status", "--porcelain", "--untracked-files=no")
+    cmd.Dir = this.Root
+    if output, err = cmd.Output(); err == nil && len(strings.TrimSpace(string(output))) > 0 {
+        revision += "-dirty"
+    }
+    return revision
+}
+
+// quoteFlag go命令按空白分隔-ldflags中的参数，包含空白的参数需要用引号括起来
+func quoteFlag(flag string) string {
+    if !strings.ContainsAny(flag, " \t\n'\"") {
+        return flag
+    }
+    if !strings.Contains(flag, "'") {
+        return "'" + flag + "'"
+    }
+    return `"` + flag + `"`
+}
@@ -0,0 +1,45 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "reflect"
+    "regexp"
+    "testing"
+)
+
+func TestBuildArgs(t *testing.T) {
+    prj := &Project{name: "web", Root: "/srv/web"}
+    if args := prj.buildArgs(); args != nil {
+        t.Errorf("buildArgs() without flags returned %q", args)
+    }
+
+    prj.SetEnv(map[string]string{"VERSION": "1.0 beta"}, nil)
+    prj.SetBuildFlags(&BuildFlags{
+        Tags:     []string{"jsoniter", "dev"},
+        Ldflags:  "-s -w",
+        Gcflags:  "all=-N -l",
+        Race:     true,
+        Trimpath: true,
+        X: map[string]string{
+            "main.buildTime": "${BUILD_TIME}",
+            "main.version":   "${VERSION}",
+            "main.name":      "${NAME}",
+        },
+        Flags: []string{"-v"},
+    })
+    args := prj.buildArgs()
+    if len(args) != 9 {
+        t.Fatalf("unexpected buildArgs(): %q", args)
+    }
+    expected := []string{"-tags", "jsoniter,dev", "-race", "-trimpath", "-gcflags", "all=-N -l", "-ldflags"}
+    if !reflect.DeepEqual(args[:7], expected) || args[8] != "-v" {
+        t.Errorf("buildArgs() returned %q", args)
+    }
+    ldflagsRe := regexp.MustCompile(`^-s -w -X main\.buildTime=\d{4}-\d\d-\d\dT\S+ -X main\.name=web -X 'main\.version=1\.0 beta'$`)
+    if !ldflagsRe.MatchString(args[7]) {
+        t.Errorf("unexpected ldflags: %s", args[7])
+    }
+}
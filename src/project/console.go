@@ -0,0 +1,72 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "sync"
+)
+
+// consoleColors 项目名称前缀的颜色（ANSI），按项目第一次输出的顺序依次使用
+var consoleColors = []string{"36", "33", "32", "35", "34", "96", "93", "92", "95", "94"}
+
+// console 所有项目进程的输出都通过它显示在autogo的控制台：每行前面加上（不同颜色的）项目名称，stderr的输出仍然写到stderr
+var console = newConsole(os.Stdout, os.Stderr, ansiColors && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout))
+
+type consoleWriter struct {
+    mu     sync.Mutex // 保证各个项目的输出按行交替，不会混在一行中
+    stdout io.Writer
+    stderr io.Writer
+    color  bool           // 是否使用颜色
+    width  int            // 项目名称对齐的宽度（目前最长的项目名称）
+    colors map[string]int // 项目使用的颜色（consoleColors的下标）
+}
+
+func newConsole(stdout, stderr io.Writer, color bool) *consoleWriter {
+    return &consoleWriter{stdout: stdout, stderr: stderr, color: color, colors: make(map[string]int)}
+}
+
+// register 为项目分配颜色，并按项目名称的长度调整对齐的宽度（Registry加入项目时调用，这样各项目按配置的顺序使用颜色）
+func (this *consoleWriter) register(name string) {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    this.add(name)
+}
+
+func (this *consoleWriter) add(name string) int {
+    if len(name) > this.width {
+        this.width = len(name)
+    }
+    index, ok := this.colors[name]
+    if !ok {
+        index = len(this.colors) % len(consoleColors)
+        this.colors[name] = index
+    }
+    return index
+}
+
+// println 输出项目name的一行输出，如"web | listening on :8080"
+func (this *consoleWriter) println(name, stream, text string) {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    index := this.add(name)
+    prefix := fmt.Sprintf("%-*s |", this.width, name)
+    if this.color {
+        prefix = "\x1b[" + consoleColors[index] + "m" + prefix + "\x1b[0m"
+    }
+    w := this.stdout
+    if stream == StreamStderr {
+        w = this.stderr
+    }
+    fmt.Fprintln(w, prefix, text)
+}
+
+// isTerminal 是否是终端（输出重定向到文件、管道时不使用颜色）
+func isTerminal(file *os.File) bool {
+    info, err := file.Stat()
+    return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
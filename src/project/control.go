@@ -0,0 +1,207 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "errors"
+    "log"
+    "time"
+)
+
+// 项目的状态（见Status）
+const (
+    StateBuilding = "building" // 正在编译、启动（或运行测试）
+    StateRunning  = "running"  // autogo启动的进程正在运行
+    StateFailed   = "failed"   // 最近一次编译、启动失败，测试没有通过，或者进程自己退出了
+    StateStopped  = "stopped"  // 没有运行（非deamon项目运行结束、被停止，或者测试都通过了）
+)
+
+// Status 项目当前状态的快照（控制API返回的内容）
+type Status struct {
+    Name      string        `json:"name"`
+    Root      string        `json:"root"`
+    GoWay     string        `json:"go_way"`
+    State     string        `json:"state"`  // building、running、failed或stopped
+    Paused    bool          `json:"paused"` // 是否暂停了监听（见Pause）
+    Pid       int           `json:"pid,omitempty"`
+    StartedAt *time.Time    `json:"started_at,omitempty"` // 进程的启动时间，没有运行时为空
+    Uptime    time.Duration `json:"uptime,omitempty"`
+    BuildTime time.Duration `json:"build_time,omitempty"` // 最近一次编译（go_way为test时是测试）的耗时，自定义脚本go run时没有
+    LastError string        `json:"last_error,omitempty"`
+    LastExit  string        `json:"last_exit,omitempty"` // 最近一次退出的进程的退出状态
+    Test      string        `json:"test,omitempty"`      // 最近一次测试的结果（见TestReport.Summary）
+    Proxy     string        `json:"proxy,omitempty"`     // 代理监听的地址
+}
+
+// Name 项目名称
+func (this *Project) Name() string {
+    return this.name
+}
+
+// Status 项目当前的状态
+func (this *Project) Status() *Status {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    status := &Status{
+        Name:      this.name,
+        Root:      this.Root,
+        GoWay:     this.GoWay,
+        Paused:    this.paused,
+        BuildTime: this.buildTime,
+        LastError: this.lastError,
+    }
+    if this.lastExit != nil {
+        status.LastExit = this.lastExit.String()
+    }
+    if this.lastTest != nil {
+        status.Test = this.lastTest.Summary()
+    }
+    if this.proxy != nil {
+        status.Proxy = this.proxy.listen
+    }
+    switch {
+    case this.building:
+        status.State = StateBuilding
+    case this.process != nil:
+        status.State = StateRunning
+    case this.lastError != "" || this.crashed || this.lastTest != nil && !this.lastTest.Passed():
+        status.State = StateFailed
+    default:
+        status.State = StateStopped
+    }
+    if this.process != nil {
+        startedAt := this.startedAt
+        status.Pid = this.process.Pid
+        status.StartedAt = &startedAt
+        status.Uptime = time.Since(startedAt).Round(time.Second)
+    }
+    return status
+}
+
+// BuildOutput 最近一次编译（或启动）的输出，失败时解析出其中的错误
+type BuildOutput struct {
+    Failed      bool         `json:"failed"`
+    Output      string       `json:"output"`
+    Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// LastBuild 最近一次编译的输出：失败时是错误信息（见LastError），同错误页面一样解析成结构化的错误
+func (this *Project) LastBuild() *BuildOutput {
+    this.mu.Lock()
+    output, failed := this.lastError, this.lastError != ""
+    if !failed {
+        output = this.buildOutput
+    }
+    this.mu.Unlock()
+    build := &BuildOutput{Failed: failed, Output: output}
+    if failed {
+        build.Diagnostics = this.diagnose(output)
+    }
+    return build
+}
+
+// setBuilding 标记是否正在编译、启动
+func (this *Project) setBuilding(building bool) {
+    this.mu.Lock()
+    this.building = building
+    this.mu.Unlock()
+}
+
+// Control 执行控制命令：rebuild（重新编译、运行）、restart（见Restart）、start、stop、pause或resume。
+// 和源码改动引起的重新编译一样依次执行，失败时执行on_failure命令
+func (this *Project) Control(command string) error {
+    if this.isClosed() {
+        return errors.New("项目" + this.name + "已关闭")
+    }
+    switch command {
+    case "rebuild":
+        log.Println("[INFO] 重新编译项目", this.name)
+        return this.rebuild(nil)
+    case "restart":
+        log.Println("[INFO] 重新启动项目", this.name)
+        return this.Restart()
+    case "start":
+        return this.start()
+    case "stop":
+        this.buildMu.Lock()
+        defer this.buildMu.Unlock()
+        return this.Stop()
+    case "pause":
+        this.Pause()
+        return nil
+    case "resume":
+        this.Resume()
+        return nil
+    }
+    return errors.New("不支持的命令：" + command)
+}
+
+// start 启动没有运行的项目（不重新编译）；go_way为run时重新编译、运行，为test时运行所有测试
+func (this *Project) start() (err error) {
+    this.buildMu.Lock()
+    defer this.buildMu.Unlock()
+    this.mu.Lock()
+    running := this.process != nil
+    this.mu.Unlock()
+    if running {
+        return errors.New("项目" + this.name + "已经在运行")
+    }
+    log.Println("[INFO] 启动项目", this.name)
+    this.setBuilding(true)
+    defer this.setBuilding(false)
+    this.proxy.Hold()
+    defer this.proxy.Release()
+    switch this.GoWay {
+    case "test":
+        err = this.retest(nil)
+    case "run":
+        err = this.Run()
+    default:
+        err = this.Start()
+    }
+    if err != nil {
+        this.runFailureHooks()
+    }
+    return err
+}
+
+// Pause 暂停处理源码改动（仍然在监听，改动会被记录），Resume之后再重新编译
+func (this *Project) Pause() {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    if !this.paused {
+        this.paused = true
+        log.Println("[INFO] 暂停监听项目", this.name)
+    }
+}
+
+// Resume 恢复处理源码改动：暂停期间有改动时，立即处理这些改动
+func (this *Project) Resume() {
+    this.mu.Lock()
+    paused, pending := this.paused, this.pending
+    this.paused, this.pending = false, nil
+    this.mu.Unlock()
+    if !paused {
+        return
+    }
+    log.Println("[INFO] 恢复监听项目", this.name)
+    if pending != nil {
+        this.dispatch(pending)
+    }
+}
+
+// hold 暂停期间，记录改动（返回true）；没有暂停时返回false，需要马上处理
+func (this *Project) hold(batch *changes) bool {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    if !this.paused {
+        return false
+    }
+    if this.pending == nil {
+        this.pending = &changes{files: make(map[string]bool)}
+    }
+    this.pending.merge(batch)
+    return true
+}
@@ -0,0 +1,183 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "files"
+    "log"
+    "path/filepath"
+    "strings"
+)
+
+// DependRoots depends的根目录（绝对路径）。GOPATH项目中相对路径是相对于项目根目录的
+func (this *Project) DependRoots() []string {
+    roots := make([]string, 0, len(this.Depends))
+    for _, depend := range this.Depends {
+        if !filepath.IsAbs(depend) {
+            depend = filepath.Join(this.Root, depend)
+        }
+        roots = append(roots, filepath.Clean(depend))
+    }
+    return roots
+}
+
+// dependSrcDirs depends中需要监听的源码目录：Go Module是根目录，GOPATH是其中的src目录（没有时是根目录）。
+// 依赖的项目也在配置中时，和它的监听目录（srcAbsolutePath）相同
+func (this *Project) dependSrcDirs() []string {
+    var dirs []string
+    for _, root := range this.DependRoots() {
+        if src := filepath.Join(root, "src"); !isModuleRoot(root) && files.IsDir(src) {
+            root = src
+        }
+        dirs = append(dirs, root)
+    }
+    return dirs
+}
+
+// watchRoot name所在的监听目录（src目录或者depends的源码目录），以及name相对于它的路径
+func (this *Project) watchRoot(name string) (root, rel string, ok bool) {
+    for _, root = range append([]string{this.srcAbsolutePath}, this.dependSrcDirs()...) {
+        rel, err := filepath.Rel(root, name)
+        if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+            return root, rel, true
+        }
+    }
+    return "", "", false
+}
+
+// dependProject 处理name的改动的项目：name在depends的源码目录中，并且该依赖是Registry中正在监听的项目，
+// 或者被某个（直接间接）依赖的项目监听着（比如共同依赖的库）时，返回这个项目。这种改动由它处理，
+// 之后按依赖顺序通知依赖它的项目（见Registry.rebuildDependents），避免重复编译
+func (this *Project) dependProject(name string) *Project {
+    if this.registry == nil {
+        return nil
+    }
+    root, _, ok := this.watchRoot(name)
+    if !ok || root == this.srcAbsolutePath {
+        return nil
+    }
+    if prj := this.registry.projectWithSrc(root); prj != nil {
+        return prj
+    }
+    for _, prj := range this.registry.Projects() {
+        if _, _, ok := prj.watchRoot(name); ok && prj != this && containsProject(this.registry.Dependents(prj), this) {
+            return prj
+        }
+    }
+    return nil
+}
+
+// containsProject projects中是否有prj
+func containsProject(projects []*Project, prj *Project) bool {
+    for _, p := range projects {
+        if p == prj {
+            return true
+        }
+    }
+    return false
+}
+
+// dependsOn 项目是否依赖prj（prj是depends中的某一个）
+func (this *Project) dependsOn(prj *Project) bool {
+    for _, dir := range this.dependSrcDirs() {
+        if dir == prj.srcAbsolutePath {
+            return true
+        }
+    }
+    return false
+}
+
+// sortByDepends 按依赖关系排序（拓扑排序）：被依赖的项目在前，没有依赖关系的项目保持原来的顺序。
+// 有循环依赖时，循环中的项目按原来的顺序放在最后
+func sortByDepends(projects []*Project) []*Project {
+    sorted := make([]*Project, 0, len(projects))
+    placed := make(map[*Project]bool, len(projects))
+    for len(sorted) < len(projects) {
+        progress := false
+        for _, prj := range projects {
+            if placed[prj] {
+                continue
+            }
+            ready := true
+            for _, depend := range projects {
+                if depend != prj && !placed[depend] && prj.dependsOn(depend) {
+                    ready = false
+                    break
+                }
+            }
+            if ready {
+                sorted = append(sorted, prj)
+                placed[prj] = true
+                progress = true
+            }
+        }
+        if !progress {
+            for _, prj := range projects {
+                if !placed[prj] {
+                    log.Println("[WARN] 项目", prj.name, "存在循环依赖")
+                    sorted = append(sorted, prj)
+                    placed[prj] = true
+                }
+            }
+        }
+    }
+    return sorted
+}
+
+// projectWithSrc 监听目录为dir的项目，没有时返回nil
+func (this *Registry) projectWithSrc(dir string) *Project {
+    for _, prj := range this.Projects() {
+        if prj.srcAbsolutePath == dir {
+            return prj
+        }
+    }
+    return nil
+}
+
+// Dependents 直接或间接依赖prj的项目，按依赖顺序排列（被依赖的在前）
+func (this *Registry) Dependents(prj *Project) []*Project {
+    projects := this.Projects()
+    found := map[*Project]bool{prj: true}
+    for changed := true; changed; {
+        changed = false
+        for _, other := range projects {
+            if found[other] {
+                continue
+            }
+            for depend := range found {
+                if other.dependsOn(depend) {
+                    found[other] = true
+                    changed = true
+                    break
+                }
+            }
+        }
+    }
+    var dependents []*Project
+    for _, other := range projects {
+        if other != prj && found[other] {
+            dependents = append(dependents, other)
+        }
+    }
+    return sortByDepends(dependents)
+}
+
+// rebuildDependents 项目的源码有改动（需要重新编译）时，按依赖顺序依次重新编译、运行依赖它的项目
+func (this *Registry) rebuildDependents(prj *Project, batch *changes) {
+    if !batch.rebuild {
+        return
+    }
+    for _, dependent := range this.Dependents(prj) {
+        if dependent.isClosed() {
+            continue
+        }
+        dependentBatch := &changes{files: batch.files, rebuild: true}
+        if dependent.hold(dependentBatch) {
+            continue
+        }
+        log.Println("[INFO] 项目", dependent.name, "依赖的项目", prj.name, "有改动，重新编译")
+        dependent.handleChanges(dependentBatch)
+    }
+}
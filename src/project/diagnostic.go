@@ -0,0 +1,170 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bufio"
+    "encoding/json"
+    "html/template"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// snippetLines 错误所在行前后各显示多少行源码
+const snippetLines = 3
+
+var (
+    errorTplFile = "templates/error.html"
+
+    tpl     *template.Template
+    tplOnce sync.Once
+
+    // diagnosticRe 匹配go build/vet输出中的一条错误：文件:行[:列]: 信息
+    diagnosticRe = regexp.MustCompile(`^(?:vet: )?(.+?\.go):(\d+)(?::(\d+))?: (.*)$`)
+)
+
+// errorTpl 错误页面模板，第一次使用时解析（模板路径相对于autogo的工作目录）
+func errorTpl() *template.Template {
+    tplOnce.Do(func() {
+        tpl = template.Must(template.ParseFiles(errorTplFile))
+    })
+    return tpl
+}
+
+// Diagnostic 编译（或vet）输出中的一条错误信息
+type Diagnostic struct {
+    Package string       `json:"package,omitempty"` // 所在包（输出中的"# 包名"）
+    File    string       `json:"file,omitempty"`    // 文件路径（go命令输出的路径）
+    Line    int          `json:"line,omitempty"`
+    Column  int          `json:"column,omitempty"`
+    Message string       `json:"message"`
+    Snippet []SourceLine `json:"snippet,omitempty"` // 出错行附近的源码
+}
+
+// SourceLine 源码中的一行
+type SourceLine struct {
+    Number  int    `json:"number"`
+    Text    string `json:"text"`
+    Current bool   `json:"current,omitempty"` // 是否是出错的那一行
+}
+
+// ParseDiagnostics 解析go build/vet的输出。不能识别为"文件:行:列: 信息"的行单独作为一条只有Message的错误，
+// 以tab开头的行是上一条错误的后续信息
+func ParseDiagnostics(output string) []Diagnostic {
+    var (
+        diagnostics []Diagnostic
+        pkg         string
+    )
+    scanner := bufio.NewScanner(strings.NewReader(output))
+    for scanner.Scan() {
+        line := strings.TrimRight(scanner.Text(), "\r")
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+        if strings.HasPrefix(line, "# ") {
+            pkg = strings.TrimSpace(line[2:])
+            continue
+        }
+        if strings.HasPrefix(line, "\t") && len(diagnostics) > 0 {
+            last := &diagnostics[len(diagnostics)-1]
+            last.Message += "\n" + strings.TrimSpace(line)
+            continue
+        }
+        matches := diagnosticRe.FindStringSubmatch(line)
+        if matches == nil {
+            diagnostics = append(diagnostics, Diagnostic{Package: pkg, Message: strings.TrimSpace(line)})
+            continue
+        }
+        lineNo, _ := strconv.Atoi(matches[2])
+        column, _ := strconv.Atoi(matches[3])
+        diagnostics = append(diagnostics, Diagnostic{
+            Package: pkg,
+            File:    matches[1],
+            Line:    lineNo,
+            Column:  column,
+            Message: matches[4],
+        })
+    }
+    return diagnostics
+}
+
+// diagnose 解析错误信息，并从项目中读取出错行附近的源码
+func (this *Project) diagnose(output string) []Diagnostic {
+    diagnostics := ParseDiagnostics(output)
+    for i := range diagnostics {
+        if diagnostics[i].File != "" {
+            diagnostics[i].Snippet = readSnippet(this.sourceFile(diagnostics[i].File), diagnostics[i].Line)
+        }
+    }
+    return diagnostics
+}
+
+// sourceFile 将go命令输出的文件路径转为绝对路径：go命令在项目根目录执行，
+// GOPATH项目中也可能是相对于src目录的路径
+func (this *Project) sourceFile(file string) string {
+    if filepath.IsAbs(file) {
+        return file
+    }
+    for _, dir := range []string{this.Root, this.srcAbsolutePath} {
+        filename := filepath.Join(dir, file)
+        if _, err := os.Stat(filename); err == nil {
+            return filename
+        }
+    }
+    return filepath.Join(this.Root, file)
+}
+
+// readSnippet 读取文件中第line行前后snippetLines行
+func readSnippet(filename string, line int) []SourceLine {
+    if line <= 0 {
+        return nil
+    }
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil
+    }
+    defer file.Close()
+    var snippet []SourceLine
+    scanner := bufio.NewScanner(file)
+    for number := 1; scanner.Scan() && number <= line+snippetLines; number++ {
+        if number >= line-snippetLines {
+            text := strings.Replace(strings.TrimRight(scanner.Text(), "\r"), "\t", "    ", -1)
+            snippet = append(snippet, SourceLine{Number: number, Text: text, Current: number == line})
+        }
+    }
+    return snippet
+}
+
+// errorPage 错误页面（templates/error.html）以及error.json的数据
+type errorPage struct {
+    Project     string       `json:"project"`
+    Output      string       `json:"output"`
+    Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// newErrorPage 根据错误输出构造错误页面的数据
+func (this *Project) newErrorPage(output string) *errorPage {
+    return &errorPage{Project: this.name, Output: output, Diagnostics: this.diagnose(output)}
+}
+
+// render 用templates/error.html输出错误页面
+func (this *errorPage) render(w io.Writer) error {
+    return errorTpl().Execute(w, this)
+}
+
+// writeJSON 将错误信息以JSON格式写入文件，方便其他工具使用
+func (this *errorPage) writeJSON(filename string) error {
+    content, err := json.MarshalIndent(this, "", "  ")
+    if err != nil {
+        return err
+    }
+    return ioutil.WriteFile(filename, content, 0666)
+}
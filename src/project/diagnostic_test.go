@@ -0,0 +1,151 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+    output := "# test\n" +
+        "src\\test\\main.go:5: imported and not used: \"io\"\n" +
+        "./main.go:12:3: cannot use x (variable of type int) as string value in argument to f\n" +
+        "\thave (int)\n" +
+        "\twant (string)\n" +
+        "go: updates to go.mod needed\n"
+
+    expected := []Diagnostic{
+        {Package: "test", File: `src\test\main.go`, Line: 5, Message: `imported and not used: "io"`},
+        {Package: "test", File: "./main.go", Line: 12, Column: 3,
+            Message: "cannot use x (variable of type int) as string value in argument to f\nhave (int)\nwant (string)"},
+        {Package: "test", Message: "go: updates to go.mod needed"},
+    }
+    diagnostics := ParseDiagnostics(output)
+    if !reflect.DeepEqual(diagnostics, expected) {
+        t.Fatalf("ParseDiagnostics() = %#v, want %#v", diagnostics, expected)
+    }
+}
+
+func TestReadSnippet(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+    filename := filepath.Join(dir, "main.go")
+    var lines []string
+    for i := 1; i <= 10; i++ {
+        lines = append(lines, fmt.Sprintf("\tline %d", i))
+    }
+    writeTestFile(t, filename, strings.Join(lines, "\n")+"\n")
+
+    tests := []struct {
+        line        int
+        first, last int // 显示的第一行和最后一行，0表示没有
+        filename    string
+    }{
+        {5, 2, 8, filename},
+        {1, 1, 4, filename}, // 文件开始
+        {2, 1, 5, filename},
+        {10, 7, 10, filename}, // 文件结束
+        {9, 6, 10, filename},
+        {0, 0, 0, filename},
+        {5, 0, 0, filepath.Join(dir, "missing.go")},
+    }
+    for _, test := range tests {
+        snippet := readSnippet(test.filename, test.line)
+        if test.first == 0 {
+            if snippet != nil {
+                t.Errorf("readSnippet(%s, %d) = %v, want nil", test.filename, test.line, snippet)
+            }
+            continue
+        }
+        if len(snippet) != test.last-test.first+1 || snippet[0].Number != test.first || snippet[len(snippet)-1].Number != test.last {
+            t.Errorf("readSnippet(%d) = %v, want lines %d-%d", test.line, snippet, test.first, test.last)
+            continue
+        }
+        for _, line := range snippet {
+            // tab展开为4个空格，只标记出错的那一行
+            if line.Text != fmt.Sprintf("    line %d", line.Number) || line.Current != (line.Number == test.line) {
+                t.Errorf("readSnippet(%d) returned line %+v", test.line, line)
+            }
+        }
+    }
+}
+
+func TestErrorPage(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    prj := newTestProject(t, dir, "broken", false, true)
+    prj.SetLogFile(LogFileOff, nil)
+    if err = prj.Compile(); err == nil {
+        t.Fatal("Compile of a broken project should fail")
+    }
+
+    // error.json：解析出的错误及出错行附近的源码
+    content, err := ioutil.ReadFile(filepath.Join(prj.errAbsolutePath, "error.json"))
+    if err != nil {
+        t.Fatalf("error.json was not written: %s", err)
+    }
+    var page errorPage
+    if err = json.Unmarshal(content, &page); err != nil {
+        t.Fatalf("error.json is invalid: %s", err)
+    }
+    if page.Project != "broken" || page.Output != prj.LastError() || len(page.Diagnostics) == 0 {
+        t.Fatalf("unexpected error.json: %s", content)
+    }
+    diagnostic := page.Diagnostics[0]
+    if diagnostic.Line != 6 || !strings.Contains(diagnostic.Message, "fmt.Prnt") {
+        t.Errorf("unexpected diagnostic: %+v", diagnostic)
+    }
+    current := 0
+    for _, line := range diagnostic.Snippet {
+        if line.Current {
+            current = line.Number
+            if !strings.Contains(line.Text, "fmt.Prnt") {
+                t.Errorf("current line is %q", line.Text)
+            }
+        }
+    }
+    if current != diagnostic.Line {
+        t.Errorf("snippet %v does not mark line %d", diagnostic.Snippet, diagnostic.Line)
+    }
+
+    // error.html：错误表格和源码
+    content, err = ioutil.ReadFile(filepath.Join(prj.errAbsolutePath, "error.html"))
+    if err != nil {
+        t.Fatalf("error.html was not written: %s", err)
+    }
+    for _, want := range []string{"项目 broken", "<td>" + filepath.Join("src", "broken.go") + "</td>", "<td>6</td>", `<span class="current">   6      fmt.Prnt(&#34;broken&#34;)</span>`} {
+        if !strings.Contains(string(content), want) {
+            t.Errorf("error.html does not contain %q:\n%s", want, content)
+        }
+    }
+
+    // 修复后重新编译，删除错误文件
+    writeTestFile(t, filepath.Join(prj.Root, "src", "broken.go"), "package main\n\nfunc main() {\n}\n")
+    if err = prj.Compile(); err != nil {
+        t.Fatalf("Compile failed: %s", err)
+    }
+    for _, name := range []string{"error.html", "error.json"} {
+        if _, err = os.Stat(filepath.Join(prj.errAbsolutePath, name)); !os.IsNotExist(err) {
+            t.Errorf("%s was not removed: %v", name, err)
+        }
+    }
+    if prj.LastError() != "" {
+        t.Errorf("LastError is still %q", prj.LastError())
+    }
+}
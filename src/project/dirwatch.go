@@ -0,0 +1,105 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "files"
+    "fsnotify"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// addWatch 使用fsnotify，监听src目录（Go Module项目是根目录）以及子目录
+func (this *Project) addWatch(watcher *fsnotify.Watcher, dir string) {
+    if this.skipWatch(dir) {
+        return
+    }
+    if err := watcher.Watch(dir); err != nil {
+        return
+    }
+    this.mu.Lock()
+    if this.watchedDirs == nil {
+        this.watchedDirs = make(map[string]bool)
+    }
+    this.watchedDirs[dir] = true
+    this.mu.Unlock()
+    for _, filename := range files.ScanDir(dir) {
+        childDir := filepath.Join(dir, filename)
+        if files.IsDir(childDir) {
+            this.addWatch(watcher, childDir)
+        }
+    }
+}
+
+// removeWatch 移除对dir以及其子目录的监听，返回dir是否正在被监听
+func (this *Project) removeWatch(watcher *fsnotify.Watcher, dir string) bool {
+    this.mu.Lock()
+    var dirs []string
+    prefix := dir + string(os.PathSeparator)
+    for watched := range this.watchedDirs {
+        if watched == dir || strings.HasPrefix(watched, prefix) {
+            dirs = append(dirs, watched)
+            delete(this.watchedDirs, watched)
+        }
+    }
+    this.mu.Unlock()
+    for _, watched := range dirs {
+        // 目录已经被删除时，内核会自动移除监听，这里的错误可以忽略
+        watcher.RemoveWatch(watched)
+    }
+    return len(dirs) > 0
+}
+
+// isWatched 目录是否正在被监听
+func (this *Project) isWatched(dir string) bool {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return this.watchedDirs[dir]
+}
+
+// handleDirEvent 处理目录的新建和删除（重命名相当于删除旧目录、新建新目录）：
+// 新建的目录（包括其中的子目录）加入监听，删除的目录（包括其中的子目录）移除监听。
+// 监听目录之外（如环境变量文件所在的项目根目录）新建的目录不处理。
+// 返回是否需要重新编译：新建的目录中有需要编译的文件，或者删除了被监听的目录
+func (this *Project) handleDirEvent(watcher *fsnotify.Watcher, event *fsnotify.FileEvent) bool {
+    dir := filepath.Clean(event.Name)
+    if event.IsCreate() && files.IsDir(dir) {
+        if _, _, ok := this.watchRoot(dir); !ok {
+            return false
+        }
+        if this.isWatched(dir) || this.skipWatch(dir) {
+            return false
+        }
+        this.addWatch(watcher, dir)
+        return this.containsMatch(dir)
+    }
+    if event.IsDelete() || event.IsRename() {
+        return this.removeWatch(watcher, dir)
+    }
+    return false
+}
+
+// containsMatch 目录中是否有需要编译的文件（比如整个包目录被移动进来）
+func (this *Project) containsMatch(dir string) bool {
+    found := false
+    filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+        if err != nil || found {
+            return filepath.SkipDir
+        }
+        if info.IsDir() {
+            if name != dir && this.skipWatch(name) {
+                return filepath.SkipDir
+            }
+            return nil
+        }
+        if rel, ok := this.relPath(name); ok && this.filter().Match(rel) {
+            found = true
+            return filepath.SkipDir
+        }
+        return nil
+    })
+    return found
+}
@@ -0,0 +1,124 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "fsnotify"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// waitEvent 从watcher中读取事件并交给handleDirEvent处理，直到收到名为name的事件；返回handleDirEvent的结果
+func waitEvent(t *testing.T, prj *Project, watcher *fsnotify.Watcher, name string) bool {
+    timeout := time.After(2 * time.Second)
+    for {
+        select {
+        case event := <-watcher.Event:
+            t.Logf("event received: %s", event)
+            rebuild := prj.handleDirEvent(watcher, event)
+            if event.Name == name {
+                return rebuild
+            }
+        case <-timeout:
+            t.Fatalf("no event received for %s", name)
+        }
+    }
+}
+
+func TestWatchNewAndDeletedDirs(t *testing.T) {
+    // Create an fsnotify watcher instance and initialize it
+    watcher, err := fsnotify.NewWatcher()
+    if err != nil {
+        t.Fatalf("NewWatcher() failed: %s", err)
+    }
+    defer watcher.Close()
+
+    const testDir string = "_test"
+
+    // Create directory to watch
+    if err = os.MkdirAll(filepath.Join(testDir, "src"), 0777); err != nil {
+        t.Fatalf("Failed to create test directory: %s", err)
+    }
+    defer os.RemoveAll(testDir)
+
+    root, _ := filepath.Abs(testDir)
+    src := filepath.Join(root, "src")
+    prj := &Project{name: "test", Root: root, srcAbsolutePath: src}
+    prj.addWatch(watcher, src)
+
+    // 新建的目录（mkdir -p）被监听，其中新建的文件会产生事件
+    newDir := filepath.Join(src, "newpkg")
+    subDir := filepath.Join(newDir, "sub")
+    if err = os.MkdirAll(subDir, 0777); err != nil {
+        t.Fatalf("Failed to create package directory: %s", err)
+    }
+    if waitEvent(t, prj, watcher, newDir) {
+        t.Error("an empty new directory should not trigger a rebuild")
+    }
+    if !prj.isWatched(newDir) || !prj.isWatched(subDir) {
+        t.Fatalf("new directories are not watched: %v", prj.watchedDirs)
+    }
+    testFile := filepath.Join(subDir, "sub.go")
+    writeTestFile(t, testFile, "package sub\n")
+    waitEvent(t, prj, watcher, testFile)
+
+    // 被排除的目录不监听
+    vendorDir := filepath.Join(src, "vendor")
+    if err = os.Mkdir(vendorDir, 0777); err != nil {
+        t.Fatalf("Failed to create vendor directory: %s", err)
+    }
+    waitEvent(t, prj, watcher, vendorDir)
+    if prj.isWatched(vendorDir) {
+        t.Error("excluded directory vendor/ should not be watched")
+    }
+
+    // 移动进来的包目录被监听，并且需要重新编译
+    movedDir := filepath.Join(src, "moved")
+    outside := filepath.Join(root, "outside")
+    writeTestFile(t, filepath.Join(outside, "moved.go"), "package moved\n")
+    if err = os.Rename(outside, movedDir); err != nil {
+        t.Fatalf("Failed to move package directory: %s", err)
+    }
+    if !waitEvent(t, prj, watcher, movedDir) {
+        t.Error("a moved in package directory should trigger a rebuild")
+    }
+    if !prj.isWatched(movedDir) {
+        t.Error("moved in directory is not watched")
+    }
+
+    // 删除的目录（包括子目录）不再监听
+    if err = os.RemoveAll(newDir); err != nil {
+        t.Fatalf("Failed to remove package directory: %s", err)
+    }
+    waitEvent(t, prj, watcher, newDir)
+    if prj.isWatched(newDir) || prj.isWatched(subDir) {
+        t.Errorf("deleted directories are still watched: %v", prj.watchedDirs)
+    }
+
+    // 重命名的目录：旧路径不再监听，新路径被监听
+    renamedDir := filepath.Join(src, "renamed")
+    if err = os.Rename(movedDir, renamedDir); err != nil {
+        t.Fatalf("Failed to rename package directory: %s", err)
+    }
+    waitEvent(t, prj, watcher, renamedDir)
+    if prj.isWatched(movedDir) || !prj.isWatched(renamedDir) {
+        t.Errorf("renamed directory is not watched correctly: %v", prj.watchedDirs)
+    }
+
+    // 监听目录之外（如环境变量文件所在的项目根目录）新建的目录不监听
+    if err = watcher.Watch(root); err != nil {
+        t.Fatalf("Failed to watch project root: %s", err)
+    }
+    pkgDir := filepath.Join(root, "pkg")
+    if err = os.Mkdir(pkgDir, 0777); err != nil {
+        t.Fatalf("Failed to create pkg directory: %s", err)
+    }
+    waitEvent(t, prj, watcher, pkgDir)
+    if prj.isWatched(pkgDir) {
+        t.Error("directory outside src should not be watched")
+    }
+}
@@ -0,0 +1,189 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "fmt"
+    "fsnotify"
+    "io/ioutil"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// envNameRe 环境变量名
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// CheckEnvName 检查环境变量名是否合法
+func CheckEnvName(name string) error {
+    if !envNameRe.MatchString(name) {
+        return errors.New("环境变量名不合法：" + name)
+    }
+    return nil
+}
+
+// SetEnv 设置项目的环境变量，编译和运行项目时都会使用。envFiles是dotenv格式的文件（相对于项目根目录），
+// 按顺序读取，env中的变量优先。每次编译、运行时重新读取这些文件，文件修改后项目会重新编译、运行
+func (this *Project) SetEnv(env map[string]string, envFiles []string) error {
+    this.env = nil
+    for name, value := range env {
+        if err := CheckEnvName(name); err != nil {
+            return err
+        }
+        this.env = append(this.env, name+"="+value)
+    }
+    sort.Strings(this.env)
+    this.envFiles = nil
+    for _, envFile := range envFiles {
+        if !filepath.IsAbs(envFile) {
+            envFile = filepath.Join(this.Root, envFile)
+        }
+        this.envFiles = append(this.envFiles, filepath.Clean(envFile))
+    }
+    return nil
+}
+
+// Environ 运行项目时的环境变量：autogo进程的环境变量，加上env_file和env中设置的变量
+func (this *Project) Environ() []string {
+    env := os.Environ()
+    for _, envFile := range this.envFiles {
+        vars, err := ReadEnvFile(envFile)
+        if err != nil {
+            log.Println("[WARN] 项目", this.name, "读取环境变量文件出错：", err)
+            continue
+        }
+        env = mergeEnv(env, vars)
+    }
+    return mergeEnv(env, this.env)
+}
+
+// isEnvFile 是否是项目的环境变量文件
+func (this *Project) isEnvFile(name string) bool {
+    name = filepath.Clean(name)
+    for _, envFile := range this.envFiles {
+        if name == envFile {
+            return true
+        }
+    }
+    return false
+}
+
+// watchEnvFiles 监听环境变量文件所在的目录（编辑器保存时可能会重命名文件，所以不直接监听文件）
+func (this *Project) watchEnvFiles(watcher *fsnotify.Watcher) {
+    for _, envFile := range this.envFiles {
+        dir := filepath.Dir(envFile)
+        if this.isWatched(dir) {
+            continue
+        }
+        if err := watcher.Watch(dir); err != nil {
+            log.Println("[WARN] 项目", this.name, "监听环境变量文件出错：", err)
+            continue
+        }
+        this.mu.Lock()
+        if this.watchedDirs == nil {
+            this.watchedDirs = make(map[string]bool)
+        }
+        this.watchedDirs[dir] = true
+        this.mu.Unlock()
+    }
+}
+
+// ReadEnvFile 读取dotenv格式的文件，返回"名称=值"形式的环境变量（按文件中的顺序）
+func ReadEnvFile(filename string) ([]string, error) {
+    content, err := ioutil.ReadFile(filename)
+    if err != nil {
+        return nil, err
+    }
+    vars, err := ParseEnv(content)
+    if err != nil {
+        return nil, errors.New(filename + ":" + err.Error())
+    }
+    return vars, nil
+}
+
+// ParseEnv 解析dotenv格式的内容：每行一个NAME=VALUE，可以有export前缀；#开始的行是注释；
+// 值可以用双引号（支持\n、\t、\"、\\转义）或单引号（原样）括起来，没有引号时" #"之后是注释
+func ParseEnv(content []byte) ([]string, error) {
+    var vars []string
+    scanner := bufio.NewScanner(bytes.NewReader(content))
+    for lineNo := 1; scanner.Scan(); lineNo++ {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+        i := strings.Index(line, "=")
+        if i < 0 {
+            return nil, fmt.Errorf("%d: 应该是NAME=VALUE的形式", lineNo)
+        }
+        name := strings.TrimSpace(line[:i])
+        if err := CheckEnvName(name); err != nil {
+            return nil, fmt.Errorf("%d: %s", lineNo, err)
+        }
+        value, err := parseEnvValue(strings.TrimSpace(line[i+1:]))
+        if err != nil {
+            return nil, fmt.Errorf("%d: %s", lineNo, err)
+        }
+        vars = append(vars, name+"="+value)
+    }
+    return vars, scanner.Err()
+}
+
+// parseEnvValue 解析等号后面的值
+func parseEnvValue(value string) (string, error) {
+    if value == "" {
+        return "", nil
+    }
+    var (
+        parsed []byte
+        rest   string
+    )
+    switch value[0] {
+    case '\'':
+        end := strings.Index(value[1:], "'")
+        if end < 0 {
+            return "", errors.New("单引号没有结束")
+        }
+        parsed, rest = []byte(value[1:end+1]), value[end+2:]
+    case '"':
+        i := 1
+        for ; i < len(value) && value[i] != '"'; i++ {
+            if value[i] != '\\' || i+1 == len(value) {
+                parsed = append(parsed, value[i])
+                continue
+            }
+            i++
+            switch value[i] {
+            case 'n':
+                parsed = append(parsed, '\n')
+            case 't':
+                parsed = append(parsed, '\t')
+            case 'r':
+                parsed = append(parsed, '\r')
+            default:
+                parsed = append(parsed, value[i])
+            }
+        }
+        if i >= len(value) {
+            return "", errors.New("双引号没有结束")
+        }
+        rest = value[i+1:]
+    default:
+        if i := strings.Index(value, " #"); i >= 0 {
+            value = value[:i]
+        }
+        return strings.TrimSpace(value), nil
+    }
+    if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+        return "", errors.New("引号之后有多余的内容：" + rest)
+    }
+    return string(parsed), nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestParseEnv(t *testing.T) {
+    vars, err := ParseEnv([]byte(`# 数据库
+DB_DSN=root:pass@tcp(127.0.0.1:3306)/test?charset=utf8
+export PORT = 8080
+EMPTY=
+URL=http://localhost/#anchor # 注释
+QUOTED="a \"b\"\nc" # 注释
+SINGLE='$HOME \n'
+`))
+    if err != nil {
+        t.Fatalf("ParseEnv failed: %s", err)
+    }
+    expected := []string{
+        "DB_DSN=root:pass@tcp(127.0.0.1:3306)/test?charset=utf8",
+        "PORT=8080",
+        "EMPTY=",
+        "URL=http://localhost/#anchor",
+        "QUOTED=a \"b\"\nc",
+        `SINGLE=$HOME \n`,
+    }
+    if !reflect.DeepEqual(vars, expected) {
+        t.Errorf("ParseEnv returned %q, expected %q", vars, expected)
+    }
+
+    for content, msg := range map[string]string{
+        "A=1\nB":           "2: 应该是NAME=VALUE的形式",
+        "1A=1":             "1: 环境变量名不合法：1A",
+        "A=\"1":            "1: 双引号没有结束",
+        "A='1' 2":          "1: 引号之后有多余的内容：2",
+        "A=1\n\nB-C=2\n":   "3: 环境变量名不合法：B-C",
+        "A=1\nB='x\nC=3\n": "2: 单引号没有结束",
+    } {
+        _, err := ParseEnv([]byte(content))
+        if err == nil || !strings.Contains(err.Error(), msg) {
+            t.Errorf("ParseEnv(%q) returned error %v, expected %q", content, err, msg)
+        }
+    }
+}
+
+func TestEnviron(t *testing.T) {
+    prj := &Project{name: "test", Root: "."}
+    if err := prj.SetEnv(map[string]string{"AUTOGO_TEST": "1", "PATH": "/autogo"}, nil); err != nil {
+        t.Fatalf("SetEnv failed: %s", err)
+    }
+    env := DefaultBuilder.Environ(prj)
+    found := 0
+    for _, kv := range env {
+        switch {
+        case kv == "AUTOGO_TEST=1", kv == "PATH=/autogo", kv == "GO111MODULE=off":
+            found++
+        case strings.HasPrefix(kv, "PATH="), strings.HasPrefix(kv, "GO111MODULE="):
+            t.Errorf("unexpected variable %s", kv)
+        }
+    }
+    if found != 3 {
+        t.Errorf("project variables were not applied: %q", env)
+    }
+    if err := prj.SetEnv(map[string]string{"A-B": "1"}, nil); err == nil {
+        t.Error("invalid variable names should be rejected")
+    }
+}
+
+func TestArgs(t *testing.T) {
+    prj := &Project{name: "web", Root: "/srv/web"}
+    prj.SetEnv(map[string]string{"PORT": "8080"}, nil)
+    prj.SetArgs("-config", "${ROOT}/conf/${NAME}.ini", "-port=$PORT", "${AUTOGO_UNDEFINED}")
+    expected := []string{"-config", "/srv/web/conf/web.ini", "-port=8080", ""}
+    if args := prj.Args(); !reflect.DeepEqual(args, expected) {
+        t.Errorf("Args() returned %q, expected %q", args, expected)
+    }
+}
@@ -0,0 +1,84 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bytes"
+    "fmt"
+    "log"
+    "strings"
+)
+
+// Hooks 编译、启动项目前后执行的命令（通过sh -c或cmd /C执行，工作目录是项目根目录，环境变量和编译时相同），
+// 同一类命令按顺序执行，一个失败后面的就不再执行
+type Hooks struct {
+    PreBuild  []string // 编译前执行，如go generate ./...；失败时不再编译
+    PostBuild []string // 编译成功后执行，如数据库迁移；go_way为run且custom_script为true时编译和运行是一步，不会执行
+    PreStart  []string // 启动项目前执行；失败时不再启动
+    OnFailure []string // 编译、启动（包括以上命令）失败后执行，如发送通知
+}
+
+// SetHooks 设置项目的hook命令，nil表示没有
+func (this *Project) SetHooks(hooks *Hooks) {
+    this.hooks = hooks
+}
+
+// commands 某一类（pre_build、post_build、pre_start、on_failure）hook命令
+func (this *Hooks) commands(kind string) []string {
+    if this == nil {
+        return nil
+    }
+    switch kind {
+    case "pre_build":
+        return this.PreBuild
+    case "post_build":
+        return this.PostBuild
+    case "pre_start":
+        return this.PreStart
+    case "on_failure":
+        return this.OnFailure
+    }
+    return nil
+}
+
+// runHooks 按顺序执行某一类hook命令，命令失败时像编译错误一样写入错误信息（错误页面）
+func (this *Project) runHooks(kind string) error {
+    for _, command := range this.hooks.commands(kind) {
+        output, err := this.runHook(kind, command)
+        if err != nil {
+            return this.writeError(strings.TrimSpace(fmt.Sprintf("hooks.%s执行失败（%s）：%s\n\n%s", kind, command, err, output)))
+        }
+    }
+    return nil
+}
+
+// runFailureHooks 编译、启动失败后执行on_failure命令，它们的失败只记录日志，不覆盖原来的错误信息
+func (this *Project) runFailureHooks() {
+    for _, command := range this.hooks.commands("on_failure") {
+        if output, err := this.runHook("on_failure", command); err != nil {
+            log.Println("[WARN] 项目", this.name, "执行hooks.on_failure出错：", command, err)
+            if output != "" {
+                fmt.Println(output)
+            }
+        }
+    }
+}
+
+// runHook 执行一个hook命令，等待其结束，返回合并后的输出；执行成功时输出打印到日志中
+func (this *Project) runHook(kind, command string) (string, error) {
+    log.Println("[INFO] 项目", this.name, "执行hooks."+kind+"：", command)
+    var output bytes.Buffer
+    cmd := shellCommand(command)
+    cmd.Dir = this.Root
+    cmd.Env = DefaultBuilder.Environ(this)
+    cmd.Stdout = &output
+    cmd.Stderr = &output
+    err := cmd.Run()
+    result := strings.TrimSpace(output.String())
+    if err == nil && result != "" {
+        fmt.Println(result)
+    }
+    return result, err
+}
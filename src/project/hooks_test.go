@@ -0,0 +1,54 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "testing"
+)
+
+func TestHooks(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("hook命令使用sh语法")
+    }
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    prj := newTestProject(t, dir, "hooks", false, false)
+    prj.SetHooks(&Hooks{
+        PreBuild:  []string{"echo $GOPATH > pre_build.txt"},
+        PostBuild: []string{"echo migrating; exit 3", "touch never.txt"},
+        OnFailure: []string{"touch on_failure.txt"},
+    })
+    err = prj.Compile()
+    if err == nil || !strings.Contains(err.Error(), "hooks.post_build") || !strings.Contains(err.Error(), "migrating") {
+        t.Fatalf("Compile() returned %v", err)
+    }
+    if prj.LastError() != err.Error() {
+        t.Errorf("LastError() returned %q", prj.LastError())
+    }
+    if _, err = os.Stat(filepath.Join(prj.errAbsolutePath, "error.html")); err != nil {
+        t.Errorf("error file was not written: %s", err)
+    }
+    // pre_build和编译时的环境变量相同
+    if content, err := ioutil.ReadFile(filepath.Join(prj.Root, "pre_build.txt")); err != nil || strings.TrimSpace(string(content)) != prj.Root {
+        t.Errorf("pre_build.txt: %q, %v", content, err)
+    }
+    if _, err = os.Stat(filepath.Join(prj.Root, "never.txt")); err == nil {
+        t.Error("hooks after a failed one should not run")
+    }
+
+    prj.runFailureHooks()
+    if _, err = os.Stat(filepath.Join(prj.Root, "on_failure.txt")); err != nil {
+        t.Errorf("on_failure was not run: %s", err)
+    }
+}
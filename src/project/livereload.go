@@ -0,0 +1,131 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bytes"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// LiveReloadPath 代理中live-reload（server-sent events）的路径，不会转发给项目
+const LiveReloadPath = "/__autogo/livereload"
+
+// liveReloadScript 注入到HTML页面中的脚本：收到reload事件后刷新页面
+var liveReloadScript = []byte(`<script>(function(){if(!window.EventSource)return;` +
+    `var es=new EventSource("` + LiveReloadPath + `");` +
+    `es.addEventListener("reload",function(){es.close();location.reload();});})();</script>`)
+
+// liveReload 管理浏览器的live-reload连接，项目重启完成或编译失败时通知所有打开的页面刷新
+type liveReload struct {
+    mu      sync.Mutex
+    clients map[chan string]bool
+}
+
+func newLiveReload() *liveReload {
+    return &liveReload{clients: make(map[chan string]bool)}
+}
+
+// broadcast 向所有连接发送事件
+func (this *liveReload) broadcast(event string) {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    for client := range this.clients {
+        select {
+        case client <- event:
+        default:
+            // 客户端来不及接收，丢弃（反正只需要刷新一次）
+        }
+    }
+}
+
+func (this *liveReload) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+    flusher, ok := rw.(http.Flusher)
+    if !ok {
+        http.Error(rw, "streaming unsupported", http.StatusInternalServerError)
+        return
+    }
+    client := make(chan string, 1)
+    this.mu.Lock()
+    this.clients[client] = true
+    this.mu.Unlock()
+    defer func() {
+        this.mu.Lock()
+        delete(this.clients, client)
+        this.mu.Unlock()
+    }()
+
+    rw.Header().Set("Content-Type", "text/event-stream")
+    rw.Header().Set("Cache-Control", "no-cache")
+    rw.WriteHeader(http.StatusOK)
+    flusher.Flush()
+
+    heartbeat := time.NewTicker(30 * time.Second)
+    defer heartbeat.Stop()
+    for {
+        select {
+        case event := <-client:
+            fmt.Fprintf(rw, "event: %s\ndata: %d\n\n", event, time.Now().Unix())
+        case <-heartbeat.C:
+            fmt.Fprint(rw, ": ping\n\n")
+        case <-req.Context().Done():
+            return
+        }
+        flusher.Flush()
+    }
+}
+
+// injectScript 在HTML的</body>（不区分大小写）之前插入live-reload脚本，没有</body>时追加到最后
+func injectScript(html []byte) []byte {
+    i := lastIndexBody(html)
+    if i < 0 {
+        return append(html, liveReloadScript...)
+    }
+    injected := make([]byte, 0, len(html)+len(liveReloadScript))
+    injected = append(injected, html[:i]...)
+    injected = append(injected, liveReloadScript...)
+    return append(injected, html[i:]...)
+}
+
+// lastIndexBody 最后一个</body>的位置。只比较ASCII字母的大小写（bytes.ToLower可能改变非ASCII字符的长度，位置会对不上）
+func lastIndexBody(html []byte) int {
+    const tag = "</body>"
+    for i := len(html) - len(tag); i >= 0; i-- {
+        match := true
+        for j := 0; j < len(tag) && match; j++ {
+            c := html[i+j]
+            if 'A' <= c && c <= 'Z' {
+                c += 'a' - 'A'
+            }
+            match = c == tag[j]
+        }
+        if match {
+            return i
+        }
+    }
+    return -1
+}
+
+// injectResponse 作为ReverseProxy.ModifyResponse，向项目返回的HTML页面中注入live-reload脚本
+func injectResponse(resp *http.Response) error {
+    if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || resp.Header.Get("Content-Encoding") != "" {
+        return nil
+    }
+    body, err := ioutil.ReadAll(resp.Body)
+    resp.Body.Close()
+    if err != nil {
+        return err
+    }
+    body = injectScript(body)
+    resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+    resp.ContentLength = int64(len(body))
+    resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+    return nil
+}
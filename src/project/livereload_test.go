@@ -0,0 +1,52 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "io/ioutil"
+    "net/http"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+func TestInjectResponse(t *testing.T) {
+    script := string(liveReloadScript)
+    tests := []struct {
+        name, contentType, encoding, body, want string
+    }{
+        {"lower case", "text/html; charset=utf-8", "", "<html><body>hi</body></html>", "<html><body>hi" + script + "</body></html>"},
+        {"upper case", "text/html", "", "<HTML><BODY>hi</BODY></HTML>", "<HTML><BODY>hi" + script + "</BODY></HTML>"},
+        {"mixed case", "text/html", "", "<body>hi</Body>", "<body>hi" + script + "</Body>"},
+        {"last body", "text/html", "", "<p>&lt;/body&gt; </body></p></body>", "<p>&lt;/body&gt; </body></p>" + script + "</body>"},
+        {"non-ascii", "text/html", "", "<body>İstanbul</body>", "<body>İstanbul" + script + "</body>"},
+        {"no body", "text/html", "", "<p>hi</p>", "<p>hi</p>" + script},
+        {"not html", "application/json", "", `{"body": "</body>"}`, `{"body": "</body>"}`},
+        {"encoded", "text/html", "gzip", "<body>hi</body>", "<body>hi</body>"},
+    }
+    for _, test := range tests {
+        resp := &http.Response{
+            Header:        make(http.Header),
+            Body:          ioutil.NopCloser(strings.NewReader(test.body)),
+            ContentLength: int64(len(test.body)),
+        }
+        resp.Header.Set("Content-Type", test.contentType)
+        resp.Header.Set("Content-Length", strconv.Itoa(len(test.body)))
+        if test.encoding != "" {
+            resp.Header.Set("Content-Encoding", test.encoding)
+        }
+        if err := injectResponse(resp); err != nil {
+            t.Errorf("%s: injectResponse failed: %s", test.name, err)
+            continue
+        }
+        body, _ := ioutil.ReadAll(resp.Body)
+        if string(body) != test.want {
+            t.Errorf("%s: body is %q, want %q", test.name, body, test.want)
+        }
+        if resp.ContentLength != int64(len(test.want)) || resp.Header.Get("Content-Length") != strconv.Itoa(len(test.want)) {
+            t.Errorf("%s: Content-Length is %d (header %s), want %d", test.name, resp.ContentLength, resp.Header.Get("Content-Length"), len(test.want))
+        }
+    }
+}
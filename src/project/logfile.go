@@ -0,0 +1,232 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "files"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    // LogFileOff 日志文件配置为off时不写日志文件
+    LogFileOff = "off"
+    // DefaultLogMaxSize 日志文件超过该大小时轮转
+    DefaultLogMaxSize = 10 << 20
+    // DefaultLogMaxBackups 默认保留的轮转后的日志文件个数
+    DefaultLogMaxBackups = 5
+
+    // logBackupTime 轮转后的日志文件名中的时间，如web-20121220-150405.log
+    logBackupTime = "20060102-150405"
+)
+
+// LogRotate 日志文件的轮转规则：文件超过MaxSize，或者写入的时间超过MaxAge时，改名为"名称-时间.log"，
+// 然后写新的文件；轮转后的文件只保留最近的MaxBackups个
+type LogRotate struct {
+    MaxSize    int64         // <=0表示DefaultLogMaxSize
+    MaxAge     time.Duration // <=0表示不按时间轮转
+    MaxBackups int           // <=0表示DefaultLogMaxBackups
+}
+
+// SetLogFile 设置项目的日志文件（进程的输出、编译的输出都会写入其中）：file是相对于项目根目录的路径，
+// 为空时是_log_/项目名称.log，为off时不写日志文件；rotate为nil时使用默认的轮转规则
+func (this *Project) SetLogFile(file string, rotate *LogRotate) {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    if this.logFile != nil {
+        this.logFile.close()
+    }
+    this.logFile = nil
+    this.logFileOff = file == LogFileOff
+    if this.logFileOff {
+        return
+    }
+    this.logFile = newRotatingFile(this.logFilePath(file), rotate)
+}
+
+// logFilePath 日志文件的绝对路径
+func (this *Project) logFilePath(file string) string {
+    if file == "" {
+        return filepath.Join(this.errAbsolutePath, this.name+".log")
+    }
+    if !filepath.IsAbs(file) {
+        file = filepath.Join(this.Root, file)
+    }
+    return filepath.Clean(file)
+}
+
+// LogFile 日志文件的路径，不写日志文件时为空
+func (this *Project) LogFile() string {
+    if file := this.logWriter(); file != nil {
+        return file.name
+    }
+    return ""
+}
+
+// logWriter 项目的日志文件，没有调用SetLogFile时使用默认配置
+func (this *Project) logWriter() *rotatingFile {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    if this.logFile == nil && !this.logFileOff && !this.closed {
+        this.logFile = newRotatingFile(this.logFilePath(""), nil)
+    }
+    return this.logFile
+}
+
+// writeLog 往日志文件中写入一行，如"2012/12/20 15:04:05 stdout | listening on :8080"
+func (this *Project) writeLog(line LogLine) {
+    if file := this.logWriter(); file != nil {
+        file.write(line.Time, fmt.Sprintf("%s %s | %s\n", line.Time.Format("2006/01/02 15:04:05"), line.Stream, line.Text))
+    }
+}
+
+// closeLog 关闭日志文件（项目Close时调用）
+func (this *Project) closeLog() {
+    this.mu.Lock()
+    file := this.logFile
+    this.logFile, this.logFileOff = nil, true
+    this.mu.Unlock()
+    if file != nil {
+        file.close()
+    }
+}
+
+// rotatingFile 按LogRotate轮转的日志文件，第一次写入时才打开
+type rotatingFile struct {
+    name   string
+    rotate LogRotate
+
+    mu      sync.Mutex
+    file    *os.File
+    size    int64
+    started time.Time // 当前文件开始写入的时间（已有的文件以最后修改时间为准）
+    stopped bool      // 打开文件失败（只警告一次）或者已经关闭，不再写入
+}
+
+func newRotatingFile(name string, rotate *LogRotate) *rotatingFile {
+    file := &rotatingFile{name: name}
+    if rotate != nil {
+        file.rotate = *rotate
+    }
+    if file.rotate.MaxSize <= 0 {
+        file.rotate.MaxSize = DefaultLogMaxSize
+    }
+    if file.rotate.MaxBackups <= 0 {
+        file.rotate.MaxBackups = DefaultLogMaxBackups
+    }
+    return file
+}
+
+// write 写入一行，需要时先轮转
+func (this *rotatingFile) write(now time.Time, line string) {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    if this.stopped {
+        return
+    }
+    if this.file == nil {
+        if err := this.open(now); err != nil {
+            this.stopped = true
+            log.Println("[WARN] 打开日志文件", this.name, "失败：", err)
+            return
+        }
+    }
+    if this.size > 0 && (this.size+int64(len(line)) > this.rotate.MaxSize || this.rotate.MaxAge > 0 && now.Sub(this.started) >= this.rotate.MaxAge) {
+        if err := this.rotateFile(now); err != nil {
+            log.Println("[WARN] 日志文件", this.name, "轮转失败：", err)
+        }
+        if this.file == nil {
+            return
+        }
+    }
+    n, _ := this.file.WriteString(line)
+    this.size += int64(n)
+}
+
+// open 打开（追加写入）日志文件
+func (this *rotatingFile) open(now time.Time) error {
+    if err := os.MkdirAll(filepath.Dir(this.name), 0777); err != nil {
+        return err
+    }
+    file, err := os.OpenFile(this.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+    if err != nil {
+        return err
+    }
+    info, err := file.Stat()
+    if err != nil {
+        file.Close()
+        return err
+    }
+    this.file, this.size, this.started = file, info.Size(), now
+    if this.size > 0 {
+        this.started = info.ModTime()
+    }
+    return nil
+}
+
+// rotateFile 将当前文件改名为"名称-时间.log"，打开新的文件，并删除多余的旧文件
+func (this *rotatingFile) rotateFile(now time.Time) error {
+    this.file.Close()
+    this.file = nil
+    ext := filepath.Ext(this.name)
+    prefix := strings.TrimSuffix(this.name, ext) + "-"
+    backup := prefix + now.Format(logBackupTime) + ext
+    for i := 1; files.Exist(backup); i++ {
+        backup = fmt.Sprintf("%s%s.%d%s", prefix, now.Format(logBackupTime), i, ext)
+    }
+    err := os.Rename(this.name, backup)
+    if e := this.open(now); e != nil {
+        this.stopped = true
+        return e
+    }
+    this.removeBackups(prefix, ext)
+    return err
+}
+
+// removeBackups 只保留最近的MaxBackups个轮转后的文件
+func (this *rotatingFile) removeBackups(prefix, ext string) {
+    infos, err := ioutil.ReadDir(filepath.Dir(prefix))
+    if err != nil {
+        return
+    }
+    base := filepath.Base(prefix)
+    var backups []string
+    for _, info := range infos {
+        name := info.Name()
+        if !strings.HasPrefix(name, base) || !strings.HasSuffix(name, ext) {
+            continue
+        }
+        stamp := strings.TrimSuffix(strings.TrimPrefix(name, base), ext)
+        if len(stamp) < len(logBackupTime) {
+            continue
+        }
+        // 排除名称以同样前缀开始的其他项目的日志文件（如web-api.log）
+        if _, err := time.Parse(logBackupTime, stamp[:len(logBackupTime)]); err == nil {
+            backups = append(backups, filepath.Join(filepath.Dir(prefix), name))
+        }
+    }
+    sort.Strings(backups)
+    for len(backups) > this.rotate.MaxBackups {
+        os.Remove(backups[0])
+        backups = backups[1:]
+    }
+}
+
+func (this *rotatingFile) close() {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    if this.file != nil {
+        this.file.Close()
+        this.file = nil
+    }
+    this.stopped = true
+}
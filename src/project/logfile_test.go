@@ -0,0 +1,71 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestRotatingFile(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo-log")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    // 名称以web-开始的其他日志文件不会被当作轮转后的文件删除
+    other := filepath.Join(dir, "web-api.log")
+    ioutil.WriteFile(other, []byte("other\n"), 0666)
+
+    now := time.Date(2012, 12, 20, 15, 0, 0, 0, time.Local)
+    file := newRotatingFile(filepath.Join(dir, "web.log"), &LogRotate{MaxSize: 10, MaxBackups: 2})
+    file.write(now, "line 1\n")
+    file.write(now, "line 2\n") // 超过10字节，轮转
+    file.write(now.Add(time.Second), "line 3\n")
+    file.write(now.Add(2*time.Second), "line 4\n") // 只保留最近的2个
+    file.close()
+    // 超过MaxAge也轮转
+    file = newRotatingFile(filepath.Join(dir, "app.log"), &LogRotate{MaxAge: time.Hour})
+    file.write(now, "a\n")
+    file.write(now.Add(30*time.Minute), "b\n")
+    file.write(now.Add(time.Hour), "c\n")
+    file.close()
+
+    infos, _ := ioutil.ReadDir(dir)
+    var names []string
+    for _, info := range infos {
+        names = append(names, info.Name())
+    }
+    expected := []string{"app-20121220-160000.log", "app.log", "web-20121220-150001.log", "web-20121220-150002.log", "web-api.log", "web.log"}
+    if strings.Join(names, " ") != strings.Join(expected, " ") {
+        t.Fatalf("expected files %v, got %v", expected, names)
+    }
+    contents := map[string]string{
+        "web.log": "line 4\n", "web-20121220-150001.log": "line 2\n", "web-20121220-150002.log": "line 3\n",
+        "app.log": "c\n", "app-20121220-160000.log": "a\nb\n",
+    }
+    for name, content := range contents {
+        if data, _ := ioutil.ReadFile(filepath.Join(dir, name)); string(data) != content {
+            t.Errorf("%s: expected %q, got %q", name, content, data)
+        }
+    }
+
+    prj := &Project{name: "web", Root: dir, errAbsolutePath: filepath.Join(dir, "_log_")}
+    if name := prj.LogFile(); name != filepath.Join(dir, "_log_", "web.log") {
+        t.Errorf("unexpected default log file: %s", name)
+    }
+    prj.SetLogFile("logs/web.log", nil)
+    if name := prj.LogFile(); name != filepath.Join(dir, "logs", "web.log") {
+        t.Errorf("unexpected log file: %s", name)
+    }
+    prj.SetLogFile(LogFileOff, nil)
+    if name := prj.LogFile(); name != "" {
+        t.Errorf("log file should be off, got %s", name)
+    }
+}
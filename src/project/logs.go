@@ -0,0 +1,171 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bytes"
+    "strings"
+    "sync"
+    "time"
+)
+
+// LogLines 每个项目保留的最近的输出行数
+const LogLines = 1000
+
+// 输出行的来源
+const (
+    StreamStdout = "stdout"
+    StreamStderr = "stderr"
+    StreamAutogo = "autogo" // autogo自己的消息，如进程启动、退出
+    StreamBuild  = "build"  // 编译的结果和输出
+)
+
+// LogLine 项目进程输出的一行
+type LogLine struct {
+    Time   time.Time `json:"time"`
+    Stream string    `json:"stream"` // stdout、stderr、autogo或build
+    Text   string    `json:"text"`
+}
+
+// logBuffer 保存项目进程最近的输出（环形缓冲区，最多LogLines行），并通知订阅者（如控制台页面）新的输出。零值可以直接使用
+type logBuffer struct {
+    mu          sync.Mutex
+    lines       []LogLine
+    start       int // 缓冲区满了之后，最早的一行的位置
+    subscribers map[chan LogLine]bool
+    closed      bool // 项目已经Close，不再有新的输出
+}
+
+// add 记录一行输出，缓冲区满了时覆盖最早的一行
+func (this *logBuffer) add(stream, text string) LogLine {
+    line := LogLine{Time: time.Now(), Stream: stream, Text: text}
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    if len(this.lines) < LogLines {
+        this.lines = append(this.lines, line)
+    } else {
+        this.lines[this.start] = line
+        this.start = (this.start + 1) % LogLines
+    }
+    for subscriber := range this.subscribers {
+        select {
+        case subscriber <- line:
+        default:
+            // 订阅者来不及接收，丢弃
+        }
+    }
+    return line
+}
+
+// subscribe 返回当前保存的输出，以及之后新的输出（调用cancel取消订阅）。项目Close后channel会被关闭
+func (this *logBuffer) subscribe() ([]LogLine, <-chan LogLine, func()) {
+    subscriber := make(chan LogLine, 100)
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    lines := this.snapshot()
+    if this.closed {
+        close(subscriber)
+        return lines, subscriber, func() {}
+    }
+    if this.subscribers == nil {
+        this.subscribers = make(map[chan LogLine]bool)
+    }
+    this.subscribers[subscriber] = true
+    return lines, subscriber, func() {
+        this.mu.Lock()
+        delete(this.subscribers, subscriber)
+        this.mu.Unlock()
+    }
+}
+
+// snapshot 按时间顺序返回保存的输出（需要持有mu）
+func (this *logBuffer) snapshot() []LogLine {
+    lines := make([]LogLine, 0, len(this.lines))
+    lines = append(lines, this.lines[this.start:]...)
+    return append(lines, this.lines[:this.start]...)
+}
+
+// close 关闭所有订阅者的channel
+func (this *logBuffer) close() {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    this.closed = true
+    for subscriber := range this.subscribers {
+        close(subscriber)
+    }
+    this.subscribers = nil
+}
+
+// lineWriter 将进程的stdout或stderr按行记录下来（见record）。
+// 不完整的行等后续输出或者flush
+type lineWriter struct {
+    prj     *Project
+    stream  string
+    partial []byte
+}
+
+// outputWriter 项目进程的stdout或stderr
+func (this *Project) outputWriter(stream string) *lineWriter {
+    return &lineWriter{prj: this, stream: stream}
+}
+
+func (this *lineWriter) Write(p []byte) (int, error) {
+    this.partial = append(this.partial, p...)
+    for {
+        i := bytes.IndexByte(this.partial, '\n')
+        if i < 0 {
+            break
+        }
+        this.line(this.partial[:i])
+        this.partial = this.partial[i+1:]
+    }
+    return len(p), nil
+}
+
+// flush 处理最后不完整的一行（进程退出后调用）
+func (this *lineWriter) flush() {
+    if len(this.partial) > 0 {
+        this.line(this.partial)
+        this.partial = nil
+    }
+}
+
+func (this *lineWriter) line(line []byte) {
+    this.prj.record(this.stream, strings.TrimRight(string(line), "\r"))
+}
+
+// record 记录一行输出：保存到Logs中并写入日志文件，进程的stdout、stderr同时显示在控制台（见console）
+func (this *Project) record(stream, text string) {
+    this.writeLog(this.logs.add(stream, text))
+    if stream == StreamStdout || stream == StreamStderr {
+        console.println(this.name, stream, text)
+    }
+}
+
+// recordBuild 记录编译的结果和输出
+func (this *Project) recordBuild(result *BuildResult, output string) {
+    if result.Success() {
+        this.record(StreamBuild, "编译成功，耗时"+result.Duration.Round(time.Millisecond).String())
+    } else {
+        this.record(StreamBuild, "编译失败，耗时"+result.Duration.Round(time.Millisecond).String())
+    }
+    if output = strings.TrimSpace(output); output != "" {
+        for _, line := range strings.Split(output, "\n") {
+            this.record(StreamBuild, strings.TrimRight(line, "\r"))
+        }
+    }
+}
+
+// Logs 项目进程最近的输出（最多LogLines行）
+func (this *Project) Logs() []LogLine {
+    this.logs.mu.Lock()
+    defer this.logs.mu.Unlock()
+    return this.logs.snapshot()
+}
+
+// SubscribeLogs 返回项目进程最近的输出，以及之后新的输出；不再需要时调用cancel
+func (this *Project) SubscribeLogs() (lines []LogLine, tail <-chan LogLine, cancel func()) {
+    return this.logs.subscribe()
+}
@@ -0,0 +1,66 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bytes"
+    "fmt"
+    "testing"
+)
+
+func TestLogBuffer(t *testing.T) {
+    var stdoutBuf, stderrBuf bytes.Buffer
+    defer func(saved *consoleWriter) { console = saved }(console)
+    console = newConsole(&stdoutBuf, &stderrBuf, false)
+
+    prj := &Project{name: "web"}
+    prj.SetLogFile(LogFileOff, nil)
+    stdout, stderr := prj.outputWriter(StreamStdout), prj.outputWriter(StreamStderr)
+    stdout.Write([]byte("hello\r\nwor"))
+    stderr.Write([]byte("oops\n"))
+    stdout.Write([]byte("ld\npartial"))
+    console.println("app2", StreamStdout, "started")
+
+    if out := stdoutBuf.String(); out != "web | hello\nweb | world\napp2 | started\n" {
+        t.Errorf("unexpected console stdout: %q", out)
+    }
+    stdoutBuf.Reset()
+    console.println("web", StreamStdout, "aligned")
+    if out := stdoutBuf.String(); out != "web  | aligned\n" {
+        t.Errorf("unexpected console stdout: %q", out)
+    }
+    if out := stderrBuf.String(); out != "web | oops\n" {
+        t.Errorf("unexpected console stderr: %q", out)
+    }
+
+    logs := &prj.logs
+    lines, tail, cancel := logs.subscribe()
+    expected := []LogLine{{Stream: "stdout", Text: "hello"}, {Stream: "stderr", Text: "oops"}, {Stream: "stdout", Text: "world"}}
+    if len(lines) != len(expected) {
+        t.Fatalf("unexpected lines: %+v", lines)
+    }
+    for i, line := range lines {
+        if line.Stream != expected[i].Stream || line.Text != expected[i].Text {
+            t.Errorf("line %d: expected %+v, got %+v", i, expected[i], line)
+        }
+    }
+    stdout.flush()
+    if line := <-tail; line.Text != "partial" {
+        t.Errorf("expected flushed partial line, got %+v", line)
+    }
+    cancel()
+
+    for i := 0; i < LogLines+10; i++ {
+        logs.add(StreamStdout, fmt.Sprint(i))
+    }
+    lines, tail, _ = logs.subscribe()
+    if len(lines) != LogLines || lines[0].Text != "10" || lines[LogLines-1].Text != fmt.Sprint(LogLines+9) {
+        t.Errorf("expected the last %d lines, got %d lines from %s", LogLines, len(lines), lines[0].Text)
+    }
+    logs.close()
+    if _, ok := <-tail; ok {
+        t.Error("subscriber channel should be closed")
+    }
+}
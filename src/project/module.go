@@ -0,0 +1,83 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bufio"
+    "files"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// IsModule 项目是否是Go Module项目（模板中使用）
+func (this *Project) IsModule() bool {
+    return this.module
+}
+
+// modFilePath 带有depends replace的临时go.mod路径（不放在项目中，避免污染项目）
+func (this *Project) modFilePath() string {
+    return filepath.Join(os.TempDir(), "autogo", this.name, "go.mod")
+}
+
+// writeModFile 复制项目的go.mod（及go.sum），并将depends作为replace追加到其中。
+// 每次编译前都重新生成，这样项目的go.mod有改动时也能生效
+func (this *Project) writeModFile() error {
+    if !this.module || len(this.Depends) == 0 {
+        return nil
+    }
+    content, err := ioutil.ReadFile(filepath.Join(this.Root, "go.mod"))
+    if err != nil {
+        return err
+    }
+    modFile := this.modFilePath()
+    if err = os.MkdirAll(filepath.Dir(modFile), 0777); err != nil {
+        return err
+    }
+    replaces := "\n"
+    for _, depend := range this.Depends {
+        replaces += "replace " + modulePath(depend) + " => " + depend + "\n"
+    }
+    if err = ioutil.WriteFile(modFile, append(content, replaces...), 0666); err != nil {
+        return err
+    }
+    // -modfile要求go.sum与之同名（后缀为.sum）
+    sum, err := ioutil.ReadFile(filepath.Join(this.Root, "go.sum"))
+    if err != nil && !os.IsNotExist(err) {
+        return err
+    }
+    return ioutil.WriteFile(strings.TrimSuffix(modFile, ".mod")+".sum", sum, 0666)
+}
+
+// modulePath 读取dir目录中go.mod的module路径，读取失败返回空字符串
+func modulePath(dir string) string {
+    file, err := os.Open(filepath.Join(dir, "go.mod"))
+    if err != nil {
+        return ""
+    }
+    defer file.Close()
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if strings.HasPrefix(line, "module") {
+            return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`)
+        }
+    }
+    return ""
+}
+
+// pathBase 模块路径（用/分隔）的最后一段
+func pathBase(importPath string) string {
+    if i := strings.LastIndex(importPath, "/"); i >= 0 {
+        return importPath[i+1:]
+    }
+    return importPath
+}
+
+// isModuleRoot 判断dir是否是Go Module的根目录
+func isModuleRoot(dir string) bool {
+    return files.IsFile(filepath.Join(dir, "go.mod"))
+}
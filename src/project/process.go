@@ -0,0 +1,138 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "os/exec"
+    "time"
+)
+
+// DefaultStopTimeout 停止项目时，发送停止信号后等待进程退出的默认时间
+const DefaultStopTimeout = 5 * time.Second
+
+// SetStop 设置停止项目的方式：signal是先发送的信号（如SIGTERM、INT，空表示SIGTERM），
+// 等待timeout（<=0表示DefaultStopTimeout）后进程还没退出，则强制杀死整个进程组
+func (this *Project) SetStop(signal string, timeout time.Duration) error {
+    sig, err := parseSignal(signal)
+    if err != nil {
+        return err
+    }
+    if timeout <= 0 {
+        timeout = DefaultStopTimeout
+    }
+    this.mu.Lock()
+    this.stopSignal = sig
+    this.stopTimeout = timeout
+    this.mu.Unlock()
+    return nil
+}
+
+// CheckSignal 检查停止信号的名称是否是SetStop支持的信号
+func CheckSignal(signal string) error {
+    _, err := parseSignal(signal)
+    return err
+}
+
+// startProcess 启动cmd（在单独的进程组中，方便停止时连同其子进程一起结束），并跟踪该进程直到它退出。
+// 返回的channel在进程退出后关闭
+func (this *Project) startProcess(cmd *exec.Cmd) (<-chan struct{}, error) {
+    setProcAttr(cmd)
+    // 输出按行实时显示在控制台并记录到Logs中，调用者设置了Stdout、Stderr时同时写入
+    stdout, stderr := this.outputWriter(StreamStdout), this.outputWriter(StreamStderr)
+    cmd.Stdout = teeWriter(cmd.Stdout, stdout)
+    cmd.Stderr = teeWriter(cmd.Stderr, stderr)
+    // 加锁启动，保证Close之后不会再有新的进程
+    this.mu.Lock()
+    if this.closed {
+        this.mu.Unlock()
+        return nil, errors.New("项目" + this.name + "已关闭")
+    }
+    if err := cmd.Start(); err != nil {
+        this.mu.Unlock()
+        return nil, err
+    }
+    exited := make(chan struct{})
+    this.process = cmd.Process
+    this.exited = exited
+    this.startedAt = time.Now()
+    this.crashed = false
+    this.mu.Unlock()
+    this.record(StreamAutogo, fmt.Sprintf("进程已启动（pid %d）", cmd.Process.Pid))
+
+    go func() {
+        cmd.Wait()
+        stdout.flush()
+        stderr.flush()
+        this.record(StreamAutogo, "进程已退出："+cmd.ProcessState.String())
+        this.mu.Lock()
+        this.lastExit = cmd.ProcessState
+        // 不是通过Stop停止的，说明进程自己退出了（比如崩溃）
+        unexpected := this.process == cmd.Process
+        if unexpected {
+            this.process = nil
+            this.crashed = this.deamon
+        }
+        this.mu.Unlock()
+        close(exited)
+        if unexpected && this.deamon {
+            log.Println("[WARN] 项目", this.name, "已退出：", cmd.ProcessState)
+        }
+    }()
+    return exited, nil
+}
+
+// teeWriter w为nil时返回lines，否则同时写入两者
+func teeWriter(w io.Writer, lines *lineWriter) io.Writer {
+    if w == nil {
+        return lines
+    }
+    return io.MultiWriter(w, lines)
+}
+
+// Stop 停止autogo启动的该Project进程（不会影响其他同名进程）：
+// 先向进程组发送停止信号，等待一段时间后还没退出，则强制杀死整个进程组
+func (this *Project) Stop() error {
+    this.mu.Lock()
+    process, exited := this.process, this.exited
+    sig, timeout := this.stopSignal, this.stopTimeout
+    this.process = nil
+    this.mu.Unlock()
+    if process == nil {
+        return nil
+    }
+
+    if sig == nil {
+        sig, _ = parseSignal("")
+    }
+    if timeout <= 0 {
+        timeout = DefaultStopTimeout
+    }
+    if err := terminate(process, sig); err != nil {
+        log.Println("[WARN] 向项目", this.name, "发送信号", sig, "失败：", err)
+    }
+    select {
+    case <-exited:
+    case <-time.After(timeout):
+        log.Println("[WARN] 项目", this.name, "在", timeout, "内没有退出，强制结束")
+        if err := killGroup(process); err != nil {
+            return err
+        }
+        <-exited
+    }
+    log.Println("[INFO] 项目", this.name, "已停止：", this.LastExit())
+    return nil
+}
+
+// LastExit 最近一次退出的进程的退出状态，没有时返回nil
+func (this *Project) LastExit() *os.ProcessState {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return this.lastExit
+}
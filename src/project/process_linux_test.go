@@ -0,0 +1,78 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "io/ioutil"
+    "strconv"
+    "strings"
+    "syscall"
+    "testing"
+    "time"
+)
+
+// processGone 进程是否已经不存在（被杀死但还没被回收的僵尸进程也算）
+func processGone(pid int) bool {
+    if syscall.Kill(pid, 0) == syscall.ESRCH {
+        return true
+    }
+    stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+    if err != nil {
+        return true
+    }
+    // 格式为"pid (名称) 状态 ..."
+    fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+    return len(fields) > 0 && fields[0] == "Z"
+}
+
+func TestStopIgnoringSignal(t *testing.T) {
+    const timeout = 500 * time.Millisecond
+
+    prj := &Project{name: "stop", deamon: true}
+    prj.SetLogFile(LogFileOff, nil)
+    if err := prj.SetStop("TERM", timeout); err != nil {
+        t.Fatalf("SetStop failed: %s", err)
+    }
+    // 忽略SIGTERM（子进程继承），并启动一个子进程，输出它的pid
+    cmd := shellCommand(`trap "" TERM; sleep 60 & echo $!; wait`)
+    output := new(safeBuffer)
+    cmd.Stdout = output
+    if _, err := prj.startProcess(cmd); err != nil {
+        t.Fatalf("startProcess failed: %s", err)
+    }
+    pid := cmd.Process.Pid
+
+    var child int
+    for deadline := time.Now().Add(2 * time.Second); child == 0 && time.Now().Before(deadline); {
+        time.Sleep(10 * time.Millisecond)
+        child, _ = strconv.Atoi(strings.TrimSpace(output.String()))
+    }
+    if child == 0 {
+        t.Fatalf("child process was not started: %q", output.String())
+    }
+
+    started := time.Now()
+    if err := prj.Stop(); err != nil {
+        t.Fatalf("Stop failed: %s", err)
+    }
+    if elapsed := time.Since(started); elapsed < timeout || elapsed > timeout+2*time.Second {
+        t.Errorf("Stop returned after %s, stop timeout is %s", elapsed, timeout)
+    }
+
+    for deadline := time.Now().Add(time.Second); !(processGone(pid) && processGone(child)) && time.Now().Before(deadline); {
+        time.Sleep(10 * time.Millisecond)
+    }
+    if !processGone(pid) || !processGone(child) {
+        t.Errorf("processes are still running: %d %d", pid, child)
+    }
+
+    state := prj.LastExit()
+    if state == nil {
+        t.Fatal("LastExit() returned nil")
+    }
+    if status := state.Sys().(syscall.WaitStatus); !status.Signaled() || status.Signal() != syscall.SIGKILL {
+        t.Errorf("LastExit() returned %s", state)
+    }
+}
@@ -6,16 +6,18 @@
 package project
 
 import (
-    "bytes"
     "errors"
     "files"
     "fmt"
     "fsnotify"
+    "hash/crc32"
     "log"
     "os"
     "os/exec"
     "path/filepath"
+    "sort"
     "strings"
+    "sync"
     "text/template"
     "time"
 )
@@ -23,24 +25,16 @@ import (
 const pathSeparator = string(os.PathSeparator)
 
 var (
-    errorTplFile = "templates/error.html"
-
-    tpl *template.Template
-
-    successFlag = "finished"
+    successFlag = "finished" // 自定义脚本（custom_script）最后输出的标志
 
     PrjRootErr = errors.New("project can't be found'!")
 )
 
-func init() {
-    tpl = template.Must(template.ParseFiles(errorTplFile))
-}
-
 // Watch 监听项目
-// 
+//
 // name：项目名称（最后生成的可执行程序名，不包括后缀）；
 // root: 项目根目录
-// goWay: 编译项目的方式，run、build还是install
+// goWay: 编译项目的方式，run、build、install，或者test（不编译运行，只运行测试）
 // deamon: 项目是否是一直运行的（即不手动退出，程序不会终止，一般会有死循环，比如Web服务）
 // mainFile：main包的main函数所在文件路径（相对于src目录）
 // depends：是依赖的其他GOPATH路径下的项目，可以不传
@@ -49,22 +43,51 @@ func Watch(name, root, goWay, mainFile string, deamon bool, depends ...string) e
     if err != nil {
         return err
     }
-    if err = prj.CreateMakeFile(); err != nil {
-        log.Println("create make file error:", err)
+    return WatchProject(prj)
+}
+
+// WatchProject 编译、运行已经创建好的项目，并监听它（需要在New之后额外设置项目时使用）
+func WatchProject(prj *Project) error {
+    if prj.CustomScript {
+        if err := prj.CreateMakeFile(); err != nil {
+            log.Println("create make file error:", err)
+            return err
+        }
+    }
+    if err := prj.proxy.Listen(); err != nil {
         return err
     }
     defer prj.Watch()
-    if goWay == "run" {
-        return prj.Run()
+    prj.buildMu.Lock()
+    defer prj.buildMu.Unlock()
+    prj.setBuilding(true)
+    defer prj.setBuilding(false)
+    prj.proxy.Hold()
+    defer prj.proxy.Release()
+    if prj.GoWay == "test" {
+        if err := prj.retest(nil); err != nil {
+            prj.runFailureHooks()
+            return err
+        }
+        return nil
+    }
+    if prj.GoWay == "run" {
+        if err := prj.Run(); err != nil {
+            prj.runFailureHooks()
+            return err
+        }
+        return nil
     }
-    if err = prj.Compile(); err != nil {
+    if err := prj.Compile(); err != nil {
+        prj.runFailureHooks()
         return err
     }
-    if err = prj.Start(); err != nil {
+    if err := prj.Start(); err != nil {
+        prj.runFailureHooks()
         return err
     }
-    if deamon {
-        log.Println("[INFO] 项目", name, "启动完成")
+    if prj.deamon {
+        log.Println("[INFO] 项目", prj.name, "启动完成")
     }
     return nil
 }
@@ -73,23 +96,69 @@ type Project struct {
     name            string   // 项目名称
     Root            string   // 项目的根路径
     binAbsolutePath string   // 执行文件路径（绝对路径）
-    execArgs        []string // 程序执行的参数
+    execArgs        []string // 程序执行的参数（可以包含${ROOT}、${NAME}以及环境变量，见SetArgs）
     srcAbsolutePath string   // 源程序文件路径（绝对路径）
     errAbsolutePath string   // 编译语法错误存放位置
 
-    GoWay   string // 项目编译方式:run、build还是install
-    deamon  bool   // 程序是否一直运行（比如Web服务）
-    Options string // 编译选项
+    GoWay   string   // 项目编译方式:run、build、install还是test
+    deamon  bool     // 程序是否一直运行（比如Web服务）
+    Options []string // 编译选项
+
+    CustomScript bool // 是否使用自定义脚本（根据templates中的模板生成install.sh/install.bat）编译
 
     // 对于run、build而言，是main包中的main函数所在路径（包括文件名）
     // 对于install而言，是main包所在目录（可能多级），如果没配置，则等于name
+    // 对于Go Module项目，是main包所在目录（相对于项目根目录，形如./cmd/name）
     MainFile string
-    Depends  []string // 依赖其他项目（一般只是库）
+    Depends  []string // 依赖其他项目（一般只是库）；Go Module项目中作为本地replace目录
+
+    module  bool   // 是否是Go Module项目（根目录有go.mod）
+    exeName string // 生成的可执行文件名（不包括后缀）
+
+    env      []string // 项目的环境变量（NAME=VALUE），见SetEnv
+    envFiles []string // 环境变量文件（绝对路径）
+
+    stopSignal  os.Signal     // 停止项目时先发送的信号
+    stopTimeout time.Duration // 发送停止信号后等待进程退出的时间，超时则强制结束
+
+    buildMu  sync.Mutex // 编译、启动、停止项目的操作依次执行，避免同时启动多个进程
+    mu       sync.Mutex
+    process  *os.Process     // autogo启动的进程（正在运行），没有时为nil
+    exited   <-chan struct{} // process退出后关闭
+    lastExit *os.ProcessState
+
+    buildFlags   *BuildFlags     // 额外的编译参数，nil表示没有
+    hooks        *Hooks          // 编译、启动前后执行的命令，nil表示没有
+    testOnChange bool            // 重新编译、启动后是否运行测试（go_way为test时总是运行）
+    vetOnChange  bool            // 源码改动后是否对改动的包执行go vet
+    lastTest     *TestReport     // 最近一次测试的结果
+    ready        *ReadyProbe     // 启动后的就绪检测，nil表示默认检测
+    watchFilter  *WatchFilter    // 哪些文件的改动触发重新编译，nil表示默认规则
+    watchedDirs  map[string]bool // 正在监听的目录
+    lastError    string          // 最近一次编译或启动失败的错误信息
+    proxy        *Proxy          // 项目的反向代理，没有配置时为nil
+
+    building  bool          // 是否正在编译、启动（见Status）
+    startedAt time.Time     // process的启动时间
+    buildTime time.Duration // 最近一次编译（go_way为test时是测试）的耗时
+    crashed   bool          // 一直运行的进程是否自己退出了（不是通过Stop停止的）
+    paused    bool          // 是否暂停处理源码改动（见Pause）
+    pending   *changes      // 暂停期间的改动，Resume时处理
 
-    process *os.Process
+    logs        logBuffer     // 进程最近的输出
+    logFile     *rotatingFile // 日志文件，第一次写入时创建（见SetLogFile）
+    logFileOff  bool          // 不写日志文件
+    buildOutput string        // 最近一次编译成功时的输出（如自定义脚本的输出）
+
+    watcher *fsnotify.Watcher // 监听源码的watcher，Watch之后才有
+    done    chan struct{}     // Close时关闭，通知监听的goroutine退出
+    closed  bool              // 是否已经Close（之后不再启动进程）
+
+    registry *Registry // 项目所在的Registry（按依赖顺序重新编译依赖它的项目），单独监听时为nil
 }
 
-// New 要求被监听项目必须有src目录（按Go习惯建目录）
+// New 对于GOPATH方式的项目，要求被监听项目必须有src目录（按Go习惯建目录）；
+// 如果项目根目录有go.mod，则按Go Module方式处理，不要求src目录
 func New(name, root, goWay, mainFile string, deamon bool, depends ...string) (*Project, error) {
     if !files.IsDir(root) {
         return nil, PrjRootErr
@@ -101,15 +170,28 @@ func New(name, root, goWay, mainFile string, deamon bool, depends ...string) (*P
     if err != nil {
         return nil, err
     }
+    if isModuleRoot(root) {
+        return newModule(name, root, goWay, mainFile, deamon, depends...)
+    }
+    srcAbsolutePath := filepath.Join(root, "src")
+    if !files.IsDir(srcAbsolutePath) {
+        return nil, PrjRootErr
+    }
     binAbsolutePath := filepath.Join(root, "bin")
 
-    options := ""
+    var options []string
+    exeName := name
     switch goWay {
+    case "test":
+        // 只运行测试，不需要main，也不会一直运行
+        deamon = false
     case "run":
         if mainFile == "" {
             mainFile = name + ".go"
         }
         mainFile = filepath.Join("src", mainFile)
+        binAbsolutePath = runBinPath(root)
+        options = []string{"-o", filepath.Join(binAbsolutePath, name+binanryFileSuffix)}
     case "build":
         if mainFile == "" {
             mainFile = name + ".go"
@@ -120,8 +202,7 @@ func New(name, root, goWay, mainFile string, deamon bool, depends ...string) (*P
                 return nil, err
             }
         }
-        output := filepath.Join("bin", name+binanryFileSuffix)
-        options = "-o " + output
+        options = []string{"-o", filepath.Join(binAbsolutePath, name+binanryFileSuffix)}
     case "install":
         fallthrough
     default:
@@ -130,286 +211,626 @@ func New(name, root, goWay, mainFile string, deamon bool, depends ...string) (*P
         } else {
             mainFile = filepath.Dir(mainFile)
         }
+        exeName = filepath.Base(mainFile)
     }
     return &Project{
         name:            name,
         Root:            root,
         binAbsolutePath: binAbsolutePath,
-        srcAbsolutePath: filepath.Join(root, "src"),
+        srcAbsolutePath: srcAbsolutePath,
         errAbsolutePath: filepath.Join(root, "_log_"),
         GoWay:           goWay,
         deamon:          deamon,
         MainFile:        mainFile,
         Options:         options,
         Depends:         depends,
+        exeName:         exeName,
     }, nil
 }
 
+// newModule 创建Go Module方式的项目：监听整个项目根目录，用go build ./cmd/x这种方式编译，
+// depends作为本地replace目录（通过-modfile使用一份临时的go.mod，不修改项目中的go.mod）
+func newModule(name, root, goWay, mainFile string, deamon bool, depends ...string) (*Project, error) {
+    binAbsolutePath := filepath.Join(root, "bin")
+
+    // main包所在目录：main配置可以是目录，也可以是目录中的某个.go文件
+    if mainFile == "" {
+        mainFile = "."
+        if files.IsDir(filepath.Join(root, "cmd", name)) {
+            mainFile = filepath.Join("cmd", name)
+        }
+    } else if strings.HasSuffix(mainFile, ".go") {
+        mainFile = filepath.Dir(mainFile)
+    }
+    mainFile = "./" + filepath.ToSlash(filepath.Clean(mainFile))
+
+    exeName := name
+    if goWay == "install" {
+        // go install生成的可执行文件名是main包所在目录名（根目录则是模块路径的最后一段）
+        exeName = filepath.Base(mainFile)
+        if mainFile == "./." {
+            exeName = pathBase(modulePath(root))
+        }
+    }
+
+    for i, depend := range depends {
+        if !filepath.IsAbs(depend) {
+            depend = filepath.Join(root, depend)
+        }
+        depend = filepath.Clean(depend)
+        if !isModuleRoot(depend) {
+            return nil, errors.New("依赖的项目" + depend + "不是Go Module（没有go.mod）！")
+        }
+        depends[i] = depend
+    }
+
+    if goWay == "test" {
+        deamon = false
+    }
+    var options []string
+    switch goWay {
+    case "run":
+        binAbsolutePath = runBinPath(root)
+        options = []string{"-o", filepath.Join(binAbsolutePath, name+binanryFileSuffix)}
+    case "build":
+        if !files.Exist(binAbsolutePath) {
+            if err := os.Mkdir(binAbsolutePath, 0777); err != nil {
+                return nil, err
+            }
+        }
+        options = []string{"-o", filepath.Join(binAbsolutePath, name+binanryFileSuffix)}
+    }
+    prj := &Project{
+        name:            name,
+        Root:            root,
+        binAbsolutePath: binAbsolutePath,
+        srcAbsolutePath: root,
+        errAbsolutePath: filepath.Join(root, "_log_"),
+        GoWay:           goWay,
+        deamon:          deamon,
+        MainFile:        mainFile,
+        Options:         options,
+        Depends:         depends,
+        module:          true,
+        exeName:         exeName,
+    }
+    if len(depends) > 0 {
+        prj.Options = append(prj.Options, "-modfile="+prj.modFilePath())
+    }
+    return prj, nil
+}
+
 // Watch 监听该项目，源码有改动会重新编译运行
 func (this *Project) Watch() error {
     watcher, err := fsnotify.NewWatcher()
     if err != nil {
         return err
     }
-    eventNum := make(chan int)
+    done := make(chan struct{})
+    this.mu.Lock()
+    if this.closed {
+        this.mu.Unlock()
+        return watcher.Close()
+    }
+    this.watcher, this.done = watcher, done
+    this.mu.Unlock()
+
+    batches := make(chan *changes)
     go func() {
         for {
-            i := 0
+            batch := &changes{files: make(map[string]bool)}
         GetEvent:
             for {
                 select {
-                case <-watcher.Event:
-                    i++
+                case event, ok := <-watcher.Event:
+                    if !ok {
+                        return
+                    }
+                    // 新建、删除目录时调整监听；只记录需要重新编译的改动（见WatchFilter）
+                    // 环境变量文件的修改也需要重新编译、运行；需要运行测试时，还要记录测试文件的改动
+                    rebuild := this.handleDirEvent(watcher, event) || this.isEnvFile(event.Name)
+                    if this.dependProject(event.Name) != nil {
+                        // 依赖的项目也在监听中，由它处理之后按依赖顺序通知（见Registry.rebuildDependents）
+                        continue
+                    }
+                    rel, ok := this.relPath(event.Name)
+                    if rebuild || ok && this.filter().Match(rel) {
+                        batch.files[event.Name] = true
+                        batch.rebuild = true
+                    } else if ok && this.testing() && isTestFile(rel) && !this.filter().SkipDir(filepath.Dir(rel)) {
+                        batch.files[event.Name] = true
+                    }
                 // 修改可能会有多次modify事件
                 case <-time.After(500e6):
                     break GetEvent
+                case <-done:
+                    return
                 }
             }
-            if i > 0 {
-                eventNum <- i
+            if len(batch.files) > 0 {
+                select {
+                case batches <- batch:
+                case <-done:
+                    return
+                }
             }
         }
     }()
 
     go func() {
         for {
-            var err error
             select {
-            case <-eventNum:
-                if this.GoWay == "run" {
-                    if err = this.Run(); err != nil {
-                        log.Println("run error，详细信息如下：")
-                        fmt.Println(err)
-                    }
-                    break
-                }
-                if err = this.Compile(); err != nil {
-                    log.Println("complie error，详细信息如下：")
-                    fmt.Println(err)
-                    break
-                }
-                if this.deamon {
-                    if err = this.Stop(); err != nil {
-                        log.Println("stop error，详细信息如下：")
-                        fmt.Println(err)
-                    }
-                }
-                if err = this.Start(); err != nil {
-                    log.Println("start error，详细信息如下：")
-                    fmt.Println(err)
+            case <-done:
+                return
+            case batch := <-batches:
+                if this.hold(batch) {
+                    continue
                 }
-            }
-            if this.deamon && err == nil {
-                log.Println("重启完成！")
+                this.dispatch(batch)
             }
         }
     }()
 
-    addWatch(watcher, this.srcAbsolutePath)
+    this.addWatch(watcher, this.srcAbsolutePath)
+    // 依赖的库有改动时也需要重新编译
+    for _, dir := range this.dependSrcDirs() {
+        this.addWatch(watcher, dir)
+    }
+    this.watchEnvFiles(watcher)
     return nil
 }
 
-// addWatch 使用fsnotify，监听src目录以及子目录
-func addWatch(watcher *fsnotify.Watcher, dir string) {
-    watcher.Watch(dir)
-    for _, filename := range files.ScanDir(dir) {
-        childDir := filepath.Join(dir, filename)
-        if files.IsDir(childDir) {
-            addWatch(watcher, childDir)
+// changes 一批（合并了500ms内的事件）文件改动
+type changes struct {
+    files   map[string]bool // 改动的文件、目录（绝对路径）
+    rebuild bool            // 是否需要重新编译（只改动了测试文件时不需要）
+}
+
+// paths 改动的文件列表
+func (this *changes) paths() []string {
+    paths := make([]string, 0, len(this.files))
+    for name := range this.files {
+        paths = append(paths, name)
+    }
+    sort.Strings(paths)
+    return paths
+}
+
+// merge 合并另一批改动
+func (this *changes) merge(other *changes) {
+    for name := range other.files {
+        this.files[name] = true
+    }
+    this.rebuild = this.rebuild || other.rebuild
+}
+
+// isTestFile 是否是测试文件（默认的监听规则排除了测试文件，它们的改动只需要运行测试）
+func isTestFile(name string) bool {
+    return strings.HasSuffix(name, "_test.go")
+}
+
+// dispatch 处理一批改动，之后按依赖顺序通知依赖该项目的项目
+func (this *Project) dispatch(batch *changes) {
+    this.handleChanges(batch)
+    if this.registry != nil {
+        this.registry.rebuildDependents(this, batch)
+    }
+}
+
+// handleChanges 处理一批文件改动：改动影响main包时重新编译、运行项目（只改动了其他包，如tools目录，则不需要），
+// 需要时对改动的包执行go vet，运行受影响的测试
+func (this *Project) handleChanges(batch *changes) {
+    if this.GoWay == "test" {
+        this.rebuild(batch.paths())
+        return
+    }
+    var affected *Affected
+    if batch.rebuild || this.testOnChange || this.vetOnChange {
+        var err error
+        if affected, err = this.Affected(batch.paths()...); err != nil {
+            log.Println("[WARN] 项目", this.name, "计算改动影响的包出错：", err)
+        }
+    }
+    if batch.rebuild {
+        if affected != nil && !this.affectsMain(affected) {
+            log.Println("[INFO] 项目", this.name, "的改动不影响main包，不需要重新编译：", affected.ChangedPackages())
+        } else if err := this.rebuild(batch.paths()); err != nil {
+            return
+        } else if this.deamon {
+            log.Println("重启完成！")
+        }
+    }
+    if affected == nil {
+        return
+    }
+    if this.vetOnChange {
+        this.vet(affected.ChangedPackages())
+    }
+    if this.testOnChange {
+        if err := this.runTests(affected.TestPackages()); err != nil {
+            log.Println("[ERROR] 项目", this.name, "运行测试出错：", err)
         }
     }
 }
 
-// SetDepends 设置依赖的项目，被依赖的项目一般是tools
-func (this *Project) SetDepends(depends ...string) {
-    for _, depend := range depends {
-        this.Depends = append(this.Depends, depend)
+// isClosed 项目是否已经Close
+func (this *Project) isClosed() bool {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return this.closed
+}
+
+// Close 停止监听该项目，并停止它的代理和进程。Close之后项目不能再使用（配置中删除了该项目或者其配置有变化时调用）
+func (this *Project) Close() error {
+    this.mu.Lock()
+    if this.closed {
+        this.mu.Unlock()
+        return nil
+    }
+    this.closed = true
+    watcher, done := this.watcher, this.done
+    this.mu.Unlock()
+
+    if done != nil {
+        close(done)
+    }
+    if watcher != nil {
+        watcher.Close()
     }
+    this.proxy.Close()
+    err := this.Stop()
+    this.logs.close()
+    this.closeLog()
+    return err
 }
 
-// ChangetoRoot 切换到当前Project的根目录
-func (this *Project) ChangeToRoot() error {
-    if err := os.Chdir(this.Root); err != nil {
-        log.Println(err)
+// rebuild 源码有改动时重新编译、运行项目（go_way为test时运行受影响的测试），失败时执行on_failure命令
+func (this *Project) rebuild(changed []string) (err error) {
+    this.buildMu.Lock()
+    defer this.buildMu.Unlock()
+    this.setBuilding(true)
+    defer this.setBuilding(false)
+    // 重新编译、启动期间，代理暂存请求
+    this.proxy.Hold()
+    defer this.proxy.Release()
+    defer func() {
+        if err != nil {
+            this.runFailureHooks()
+        }
+    }()
+    if this.GoWay == "test" {
+        if err = this.retest(changed); err != nil {
+            log.Println("test error，详细信息如下：")
+            fmt.Println(err)
+        }
         return err
     }
-    return nil
+    if this.GoWay == "run" && this.CustomScript {
+        if this.deamon {
+            if err = this.Stop(); err != nil {
+                log.Println("stop error，详细信息如下：")
+                fmt.Println(err)
+            }
+        }
+        if err = this.Run(); err != nil {
+            log.Println("run error，详细信息如下：")
+            fmt.Println(err)
+        }
+        return err
+    }
+    if err = this.Compile(); err != nil {
+        log.Println("complie error，详细信息如下：")
+        fmt.Println(err)
+        return err
+    }
+    if this.deamon {
+        if err = this.Stop(); err != nil {
+            log.Println("stop error，详细信息如下：")
+            fmt.Println(err)
+        }
+    }
+    if err = this.Start(); err != nil {
+        log.Println("start error，详细信息如下：")
+        fmt.Println(err)
+    }
+    return err
+}
+
+// SetArgs 设置程序执行的参数，可以在项目运行期间修改（之后调用Restart生效）。参数中的${ROOT}、${NAME}
+// 分别是项目根目录和项目名称，其他${VAR}、$VAR是项目的环境变量（见Environ），在启动时展开
+func (this *Project) SetArgs(args ...string) {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    this.execArgs = append([]string(nil), args...)
+}
+
+// Args 展开后的程序参数
+func (this *Project) Args() []string {
+    this.mu.Lock()
+    args := this.execArgs
+    this.mu.Unlock()
+    if len(args) == 0 {
+        return nil
+    }
+    vars := this.vars()
+    expanded := make([]string, len(args))
+    for i, arg := range args {
+        expanded[i] = expand(arg, vars)
+    }
+    return expanded
+}
+
+// vars 程序参数、编译参数中可以使用的变量：项目的环境变量，以及ROOT（项目根目录）、NAME（项目名称）
+func (this *Project) vars() map[string]string {
+    vars := make(map[string]string)
+    for _, kv := range this.Environ() {
+        if i := strings.Index(kv, "="); i > 0 {
+            vars[kv[:i]] = kv[i+1:]
+        }
+    }
+    vars["ROOT"], vars["NAME"] = this.Root, this.name
+    return vars
+}
+
+// expand 展开s中的${VAR}、$VAR，没有的变量为空
+func expand(s string, vars map[string]string) string {
+    return os.Expand(s, func(name string) string {
+        return vars[name]
+    })
+}
+
+// SetDepends 设置依赖的项目，被依赖的项目一般是tools
+func (this *Project) SetDepends(depends ...string) {
+    for _, depend := range depends {
+        this.Depends = append(this.Depends, depend)
+    }
 }
 
 // CreateMakeFile 创建make文件（在当前工程根目录），这里的make文件和makefile不一样
 // 这里的make文件只是方便编译当前工程而不依赖于GOPATH
 func (this *Project) CreateMakeFile() error {
-    // 获得当前目录
-    path, err := os.Getwd()
-    if err != nil {
-        return err
-    }
-    this.ChangeToRoot()
     file, err := os.Create(filepath.Join(this.Root, installFileName))
     if err != nil {
-        os.Chdir(path)
         return err
     }
-    os.Chdir(path)
     defer file.Close()
     tpl := template.Must(template.ParseFiles(makeTplFile))
     tpl.Execute(file, this)
     return nil
 }
 
-// Run 当GoWay==run时，直接通过该方法，而不需要先Compile然后Start
+// Run 当GoWay==run时，通过该方法编译、运行：先编译到临时目录（见runBinPath），编译通过后再Start，
+// 这样就绪检测不包含编译的时间，编译出错时也和build一样写入错误页面。
+// 自定义脚本（custom_script）的编译和运行是一步，所以之前依次执行pre_build、pre_start命令
 func (this *Project) Run() error {
-    path, err := os.Getwd()
+    if !this.CustomScript {
+        if err := this.Compile(); err != nil {
+            return err
+        }
+        return this.Start()
+    }
+    err := this.writeModFile()
     if err != nil {
         return err
     }
-    this.ChangeToRoot()
-    defer os.Chdir(path)
-    os.Chmod(installFileName, 0755)
-    cmd := exec.Command(installCmd)
-    var stdout bytes.Buffer
-    var stderr bytes.Buffer
-    cmd.Stdout = &stdout
-    cmd.Stderr = &stderr
-    if err = cmd.Start(); err != nil {
+    if err = this.runHooks("pre_build"); err != nil {
+        return err
+    }
+    if err = this.runHooks("pre_start"); err != nil {
         return err
     }
-    // TODO:据说time.Sleep会内存泄露
-    select {
-    case <-time.After(300e6):
+    cmd := this.scriptCommand()
+    output := new(safeBuffer)
+    cmd.Stdout = output
+    cmd.Stderr = output
+    started := time.Now()
+    exited, err := this.startProcess(cmd)
+    if err != nil {
+        return err
     }
-    output := strings.TrimSpace(stdout.String())
-    errOutput := strings.TrimSpace(stderr.String())
-    errFile := filepath.Join(this.errAbsolutePath, "error.html")
+
     if this.deamon {
-        if output == "" {
-            // 删除可能的错误文件夹和文件
-            if files.Exist(errFile) {
-                os.RemoveAll(this.errAbsolutePath)
-            }
-            this.process = cmd.Process
+        // 编译通过且已经就绪；否则（编译出错、提前退出或超时）写入错误信息
+        err = this.waitReady(started, exited, output)
+        if err == nil {
+            this.clearError()
             return nil
         }
+        this.Stop()
+        log.Println("[ERROR] 项目", this.name, "启动失败：", err)
     } else {
-        log.Println("=====================")
-        log.Println("[INFO] 项目", this.name, "的运行结果:")
-        for _, val := range strings.Split(errOutput, "\n") {
-            fmt.Println(val)
-        }
-        log.Println("=====================")
-        return nil
-    }
-
-    if strings.Contains(errOutput, "listen tcp") {
-        if err = this.process.Kill(); err == nil {
-            this.Run()
-        }
-        return nil
-    }
-
-    // 往项目中写入错误信息
-    if !files.Exist(this.errAbsolutePath) {
-        if err = os.Mkdir(this.errAbsolutePath, 0777); err != nil {
-            log.Println("can't create errAbsolutePath: ", err)
+        <-exited
+        if cmd.ProcessState.Success() {
+            log.Println("[INFO] 项目", this.name, "运行结束")
+            return nil
         }
     }
-    file, err := os.Create(errFile)
-    if err != nil {
-        return err
-    }
-    defer file.Close()
-    tpl.Execute(file, errOutput)
-    return errors.New(errOutput)
+    return this.writeError(trimSuccessFlag(strings.TrimSpace(output.String())))
 }
 
-// Compile 编译当前Project。
+// Compile 编译当前Project：编译前执行pre_build命令，编译成功后执行post_build命令
 func (this *Project) Compile() error {
-    path, err := os.Getwd()
-    if err != nil {
-        return err
-    }
-    this.ChangeToRoot()
-    defer os.Chdir(path)
     // 删除bin中的文件
-    if this.GoWay == "build" {
+    if this.GoWay == "build" || this.GoWay == "run" {
         binFile := this.getExeFilePath()
         if files.Exist(binFile) {
             os.Remove(binFile)
         }
     }
-    os.Chmod(installFileName, 0755)
-    cmd := exec.Command(installCmd)
-    var stdout bytes.Buffer
-    cmd.Stdout = &stdout
-    if err = cmd.Run(); err != nil {
+    if this.GoWay == "run" {
+        // 临时目录可能被清理过
+        if err := os.MkdirAll(this.binAbsolutePath, 0777); err != nil {
+            return err
+        }
+    }
+    if err := this.writeModFile(); err != nil {
         return err
     }
-    output := strings.TrimSpace(stdout.String())
-    errFile := filepath.Join(this.errAbsolutePath, "error.html")
-    if successFlag == output {
-        // 删除可能的错误文件夹和文件
-        if files.Exist(errFile) {
-            os.RemoveAll(this.errAbsolutePath)
+    if err := this.runHooks("pre_build"); err != nil {
+        return err
+    }
+    result, err := DefaultBuilder.Build(this)
+    if err != nil {
+        return err
+    }
+    this.mu.Lock()
+    this.buildTime = result.Duration
+    this.mu.Unlock()
+    output := result.Output()
+    if this.CustomScript {
+        output = trimSuccessFlag(output)
+    }
+    this.recordBuild(result, output)
+    if result.Success() {
+        this.mu.Lock()
+        this.buildOutput = output
+        this.mu.Unlock()
+        if err = this.runHooks("post_build"); err != nil {
+            return err
         }
+        this.clearError()
         return nil
     }
+    return this.writeError(output)
+}
+
+// clearError 编译成功后，删除可能的错误文件（_log_中的测试结果等其他文件保留）
+func (this *Project) clearError() {
+    this.mu.Lock()
+    this.lastError = ""
+    this.mu.Unlock()
+    for _, name := range []string{"error.html", "error.json"} {
+        if filename := filepath.Join(this.errAbsolutePath, name); files.Exist(filename) {
+            os.Remove(filename)
+        }
+    }
+}
 
-    // 往项目中写入错误信息
+// LastError 最近一次编译、启动失败的错误信息，成功时为空
+func (this *Project) LastError() string {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return this.lastError
+}
+
+// writeError 往项目中写入错误信息，返回以错误信息构造的error
+func (this *Project) writeError(output string) error {
+    this.mu.Lock()
+    this.lastError = output
+    this.mu.Unlock()
     if !files.Exist(this.errAbsolutePath) {
-        if err = os.Mkdir(this.errAbsolutePath, 0777); err != nil {
+        if err := os.Mkdir(this.errAbsolutePath, 0777); err != nil {
             log.Println("can't create errAbsolutePath: ", err)
         }
     }
-    file, err := os.Create(errFile)
+    file, err := os.Create(filepath.Join(this.errAbsolutePath, "error.html"))
     if err != nil {
         return err
     }
     defer file.Close()
-    output = strings.Replace(output, "finished", "", -1)
-    tpl.Execute(file, output)
+    page := this.newErrorPage(output)
+    page.render(file)
+    if err = page.writeJSON(filepath.Join(this.errAbsolutePath, "error.json")); err != nil {
+        log.Println("can't write error.json: ", err)
+    }
     return errors.New(output)
 }
 
-// Start 启动该Project
+// trimSuccessFlag 去掉自定义脚本最后输出的successFlag那一行
+func trimSuccessFlag(output string) string {
+    lines := strings.Split(output, "\n")
+    for i, line := range lines {
+        if strings.TrimSpace(line) == successFlag {
+            lines = append(lines[:i], lines[i+1:]...)
+            break
+        }
+    }
+    return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// scriptCommand 自定义脚本（custom_script）方式编译时执行的命令
+func (this *Project) scriptCommand() *exec.Cmd {
+    os.Chmod(filepath.Join(this.Root, installFileName), 0755)
+    cmd := exec.Command(filepath.Join(this.Root, installFileName))
+    cmd.Dir = this.Root
+    cmd.Env = this.Environ()
+    return cmd
+}
+
+// Start 启动该Project，启动前执行pre_start命令
 func (this *Project) Start() error {
-    path, err := os.Getwd()
-    if err != nil {
+    if err := this.runHooks("pre_start"); err != nil {
         return err
     }
-    this.ChangeToRoot()
-    defer os.Chdir(path)
-    cmd := exec.Command(this.getExeFilePath(), this.execArgs...)
-    var stdout bytes.Buffer
-    cmd.Stdout = &stdout
-    err = cmd.Start()
+    cmd := exec.Command(this.getExeFilePath(), this.Args()...)
+    cmd.Dir = this.Root
+    cmd.Env = this.Environ()
+    // 输出实时显示在控制台（见startProcess）；deamon还需要暂存启动阶段的输出，用于就绪检测和启动失败时的错误信息
+    var output *safeBuffer
     if this.deamon {
+        output = new(safeBuffer)
+        cmd.Stdout = output
+        cmd.Stderr = output
+    }
+    started := time.Now()
+    exited, err := this.startProcess(cmd)
+    if err != nil {
         return err
     }
 
-    if err = cmd.Wait(); err != nil {
-        return errors.New("启动失败!")
+    if this.deamon {
+        if err = this.waitReady(started, exited, output); err != nil {
+            this.Stop()
+            return this.writeError(strings.TrimSpace("启动失败：" + err.Error() + "\n\n" + output.String()))
+        }
+        this.clearError()
+        return nil
     }
-    output := strings.TrimSpace(stdout.String())
-    log.Println("=====================")
-    log.Println("[INFO] 项目", this.name, "的运行结果:")
-    for _, val := range strings.Split(output, "\n") {
-        fmt.Println(val)
+
+    <-exited
+    if !cmd.ProcessState.Success() {
+        return errors.New("启动失败!" + cmd.ProcessState.String())
     }
-    log.Println("=====================")
+    log.Println("[INFO] 项目", this.name, "运行结束")
     return nil
 }
 
-// 重新启动该Project
-func (this *Project) Restart() error {
-    if err := this.Stop(); err != nil {
+// Restart 重新启动该Project（不重新编译，比如修改了程序参数之后）；go_way为run时重新编译、运行，为test时重新运行所有测试
+func (this *Project) Restart() (err error) {
+    this.buildMu.Lock()
+    defer this.buildMu.Unlock()
+    this.setBuilding(true)
+    defer this.setBuilding(false)
+    this.proxy.Hold()
+    defer this.proxy.Release()
+    if this.GoWay == "test" {
+        if err = this.retest(nil); err != nil {
+            this.runFailureHooks()
+        }
+        return err
+    }
+    if err = this.Stop(); err != nil {
         log.Println("stop project error! 信息信息如下：")
         fmt.Println(err)
         return err
     }
-    return this.Start()
+    if this.GoWay == "run" {
+        err = this.Run()
+    } else {
+        err = this.Start()
+    }
+    if err != nil {
+        this.runFailureHooks()
+    }
+    return err
+}
+
+// runBinPath go_way为run时可执行文件所在的目录：系统临时目录中按项目根目录区分的子目录，不在项目中生成bin
+func runBinPath(root string) string {
+    return filepath.Join(os.TempDir(), "autogo", fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(root))))
 }
 
 // getExeFilePath 获得可执行文件路径（项目）
 func (this *Project) getExeFilePath() string {
-    return filepath.Join(this.binAbsolutePath, this.MainFile+binanryFileSuffix)
+    return filepath.Join(this.binAbsolutePath, this.exeName+binanryFileSuffix)
 }
@@ -5,22 +5,63 @@
 package project
 
 import (
+    "errors"
+    "os"
     "os/exec"
+    "strings"
+    "syscall"
 )
 
 var (
     makeTplFile       = "templates/make_linux.tpl"
     installFileName   = "install.sh"
     binanryFileSuffix = ""
-
-    installCmd = "./" + installFileName // 编译时传给Command的名称
+    // ansiColors 终端支持ANSI颜色（见console）
+    ansiColors = true
 )
 
-// Stop 停止该Project
-func (this *Project) Stop() error {
-    cmd := exec.Command("killall", this.MainFile)
-    if err := cmd.Run(); err != nil {
-        return err
+var signals = map[string]syscall.Signal{
+    "TERM": syscall.SIGTERM,
+    "INT":  syscall.SIGINT,
+    "QUIT": syscall.SIGQUIT,
+    "HUP":  syscall.SIGHUP,
+    "KILL": syscall.SIGKILL,
+    "USR1": syscall.SIGUSR1,
+    "USR2": syscall.SIGUSR2,
+}
+
+// parseSignal 解析信号名称，如SIGTERM、TERM（不区分大小写），空字符串表示SIGTERM
+func parseSignal(name string) (os.Signal, error) {
+    name = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+    if name == "" {
+        return syscall.SIGTERM, nil
+    }
+    if sig, ok := signals[name]; ok {
+        return sig, nil
     }
-    return nil
+    return nil, errors.New("不支持的信号：" + name)
+}
+
+// setProcAttr 让进程在自己的进程组中运行
+func setProcAttr(cmd *exec.Cmd) {
+    cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminate 向进程所在的进程组发送信号
+func terminate(process *os.Process, sig os.Signal) error {
+    err := syscall.Kill(-process.Pid, sig.(syscall.Signal))
+    if err == syscall.ESRCH {
+        return nil
+    }
+    return err
+}
+
+// killGroup 强制杀死进程所在的进程组
+func killGroup(process *os.Process) error {
+    return terminate(process, syscall.SIGKILL)
+}
+
+// shellCommand 通过sh执行命令行（hook命令）
+func shellCommand(command string) *exec.Cmd {
+    return exec.Command("sh", "-c", command)
 }
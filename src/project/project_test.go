@@ -0,0 +1,139 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "fmt"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+)
+
+func init() {
+    // 测试在src/project目录中运行，模板相对于autogo根目录
+    errorTplFile = filepath.Join("..", "..", "templates", "error.html")
+    testTplFile = filepath.Join("..", "..", "templates", "test.html")
+}
+
+// writeTestFile 创建文件（包括其所在目录）
+func writeTestFile(t *testing.T, filename, content string) {
+    if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+        t.Fatalf("Failed to create directory: %s", err)
+    }
+    if err := ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+        t.Fatalf("Failed to create file: %s", err)
+    }
+}
+
+// newTestProject 在dir中创建一个输出自己名称的项目：module为true时是Go Module项目，否则是GOPATH项目；
+// broken为true时项目有编译错误
+func newTestProject(t *testing.T, dir, name string, module, broken bool) *Project {
+    root := filepath.Join(dir, name)
+    mainFile := name + ".go"
+    source := "package main\n\nimport \"fmt\"\n\nfunc main() {\n    fmt.Print(\"" + name + "\")\n}\n"
+    if broken {
+        source = strings.Replace(source, "fmt.Print", "fmt.Prnt", 1)
+    }
+    if module {
+        writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/"+name+"\n\ngo 1.16\n")
+        writeTestFile(t, filepath.Join(root, "cmd", name, "main.go"), source)
+        mainFile = ""
+    } else {
+        writeTestFile(t, filepath.Join(root, "src", mainFile), source)
+    }
+    prj, err := New(name, root, "build", mainFile, false)
+    if err != nil {
+        t.Fatalf("New(%s) failed: %s", name, err)
+    }
+    return prj
+}
+
+func TestCompileConcurrently(t *testing.T) {
+    const testDir string = "_test"
+
+    if err := os.Mkdir(testDir, 0777); err != nil {
+        t.Fatalf("Failed to create test directory: %s", err)
+    }
+    defer os.RemoveAll(testDir)
+
+    wd, err := os.Getwd()
+    if err != nil {
+        t.Fatalf("Getwd() failed: %s", err)
+    }
+
+    var projects []*Project
+    for i := 0; i < 6; i++ {
+        name := fmt.Sprintf("prj%d", i)
+        projects = append(projects, newTestProject(t, testDir, name, i%2 == 1, i == 4))
+    }
+
+    var wg sync.WaitGroup
+    errs := make([]error, len(projects))
+    for i, prj := range projects {
+        wg.Add(1)
+        go func(i int, prj *Project) {
+            defer wg.Done()
+            if err := prj.Compile(); err != nil && i != 4 {
+                errs[i] = err
+            }
+        }(i, prj)
+    }
+    wg.Wait()
+
+    if cwd, _ := os.Getwd(); cwd != wd {
+        t.Fatalf("working directory changed: %s -> %s", wd, cwd)
+    }
+
+    for i, prj := range projects {
+        if errs[i] != nil {
+            t.Errorf("Compile(%s) failed: %s", prj.name, errs[i])
+            continue
+        }
+        errFile := filepath.Join(prj.errAbsolutePath, "error.html")
+        if i == 4 {
+            if _, err := os.Stat(errFile); err != nil {
+                t.Errorf("%s: error file was not written: %s", prj.name, err)
+            }
+            continue
+        }
+        if _, err := os.Stat(errFile); err == nil {
+            t.Errorf("%s: unexpected error file", prj.name)
+        }
+        output, err := exec.Command(prj.getExeFilePath()).Output()
+        if err != nil {
+            t.Errorf("running %s failed: %s", prj.name, err)
+            continue
+        }
+        if string(output) != prj.name {
+            t.Errorf("%s: binary printed %q", prj.name, output)
+        }
+    }
+}
+
+func TestModuleDepends(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    lib := filepath.Join(dir, "lib")
+    writeTestFile(t, filepath.Join(lib, "go.mod"), "module example.com/lib\n\ngo 1.16\n")
+    newTestProject(t, dir, "app", true, false)
+    // 相对路径是相对于项目根目录的，绝对路径保持不变
+    for _, depend := range []string{"../lib", lib} {
+        prj, err := New("app", filepath.Join(dir, "app"), "build", "", false, depend)
+        if err != nil {
+            t.Fatalf("New with depend %s failed: %s", depend, err)
+        }
+        if len(prj.Depends) != 1 || prj.Depends[0] != lib {
+            t.Errorf("depend %s was resolved to %v", depend, prj.Depends)
+        }
+    }
+}
@@ -5,22 +5,41 @@
 package project
 
 import (
+    "os"
     "os/exec"
+    "strconv"
+    "syscall"
 )
 
 var (
     makeTplFile       = "templates/make_win.tpl"
     installFileName   = "install.bat"
     binanryFileSuffix = ".exe"
-
-    installCmd = installFileName // 编译时传给Command的名称
+    // ansiColors windows的控制台默认不支持ANSI颜色（见console）
+    ansiColors = false
 )
 
-// Stop 停止该Project
-func (this *Project) Stop() error {
-    cmd := exec.Command("taskkill", "/F", "/IM", this.MainFile+binanryFileSuffix)
-    if err := cmd.Run(); err != nil {
-        return err
-    }
-    return nil
+// parseSignal windows下没有信号，停止时总是先通过taskkill请求进程退出
+func parseSignal(name string) (os.Signal, error) {
+    return os.Interrupt, nil
+}
+
+// setProcAttr 让进程在新的进程组中运行
+func setProcAttr(cmd *exec.Cmd) {
+    cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminate 请求进程及其子进程退出
+func terminate(process *os.Process, sig os.Signal) error {
+    return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(process.Pid)).Run()
+}
+
+// killGroup 强制结束进程及其子进程
+func killGroup(process *os.Process) error {
+    return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(process.Pid)).Run()
+}
+
+// shellCommand 通过cmd执行命令行（hook命令）
+func shellCommand(command string) *exec.Cmd {
+    return exec.Command("cmd", "/C", command)
 }
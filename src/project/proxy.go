@@ -0,0 +1,209 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bytes"
+    "errors"
+    "log"
+    "net"
+    "net/http"
+    "net/http/httputil"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+var (
+    // ProxyHoldTimeout 重新编译、启动期间，请求最多被暂存的时间，超时后直接转发
+    ProxyHoldTimeout = 60 * time.Second
+    // ProxyReadyTimeout 项目启动后，等待其监听端口的最长时间
+    ProxyReadyTimeout = 10 * time.Second
+)
+
+// Proxy 项目的反向代理：项目重新编译、启动期间暂存请求，等新的程序开始监听后再转发；
+// 最近一次编译失败时，直接返回错误页面。这样被监控的项目不需要自己读取_log_/error.html
+type Proxy struct {
+    prj     *Project
+    listen  string   // 代理监听的地址，如:3000
+    target  *url.URL // 项目本身监听的地址
+    reverse *httputil.ReverseProxy
+    reload  *liveReload // 没有开启live-reload时为nil
+    server  *http.Server
+
+    mu         sync.Mutex
+    gate       chan struct{} // 不为nil时表示正在编译、启动，请求等待它关闭
+    generation int           // 每次Hold加1，避免旧的Release放行新一轮编译期间的请求
+}
+
+// SetProxy 为项目配置反向代理：listen是代理监听的地址，target是项目自己监听的地址（如:8080或http://127.0.0.1:8080）；
+// liveReload为true时，向HTML页面注入脚本，项目重启完成或编译出错时，打开的页面自动刷新
+func (this *Project) SetProxy(listen, target string, liveReload bool) error {
+    if listen == "" {
+        this.proxy = nil
+        return nil
+    }
+    targetURL, err := parseTarget(target)
+    if err != nil {
+        return err
+    }
+    proxy := &Proxy{
+        prj:     this,
+        listen:  listen,
+        target:  targetURL,
+        reverse: httputil.NewSingleHostReverseProxy(targetURL),
+    }
+    if liveReload {
+        proxy.reload = newLiveReload()
+        director := proxy.reverse.Director
+        proxy.reverse.Director = func(req *http.Request) {
+            director(req)
+            // 不要压缩，方便注入脚本
+            req.Header.Del("Accept-Encoding")
+        }
+        proxy.reverse.ModifyResponse = injectResponse
+    }
+    this.proxy = proxy
+    return nil
+}
+
+// CheckProxyTarget 检查代理目标地址是否是SetProxy支持的格式
+func CheckProxyTarget(target string) error {
+    _, err := parseTarget(target)
+    return err
+}
+
+// parseTarget 解析代理目标地址，省略协议时为http，省略主机时为127.0.0.1
+func parseTarget(target string) (*url.URL, error) {
+    if target == "" {
+        return nil, errors.New("配置了proxy_listen时，proxy_target不能为空！")
+    }
+    if !strings.Contains(target, "://") {
+        if strings.HasPrefix(target, ":") {
+            target = "127.0.0.1" + target
+        }
+        target = "http://" + target
+    }
+    targetURL, err := url.Parse(target)
+    if err != nil {
+        return nil, err
+    }
+    if targetURL.Port() == "" {
+        return nil, errors.New("proxy_target必须包含端口：" + target)
+    }
+    return targetURL, nil
+}
+
+// Listen 开始监听，在单独的goroutine中处理请求
+func (this *Proxy) Listen() error {
+    if this == nil {
+        return nil
+    }
+    listener, err := net.Listen("tcp", this.listen)
+    if err != nil {
+        return err
+    }
+    log.Println("[INFO] 项目", this.prj.name, "的代理", this.listen, "=>", this.target)
+    this.server = &http.Server{Handler: this}
+    go this.server.Serve(listener)
+    return nil
+}
+
+// Close 停止监听，并断开所有连接（包括live-reload连接）
+func (this *Proxy) Close() error {
+    if this == nil || this.server == nil {
+        return nil
+    }
+    return this.server.Close()
+}
+
+// Hold 开始编译、启动项目，之后的请求将被暂存
+func (this *Proxy) Hold() {
+    if this == nil {
+        return
+    }
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    this.generation++
+    if this.gate == nil {
+        this.gate = make(chan struct{})
+    }
+}
+
+// Release 编译、启动结束：编译失败则立即放行（返回错误页面），否则等项目开始监听后再放行暂存的请求
+func (this *Proxy) Release() {
+    if this == nil {
+        return
+    }
+    this.mu.Lock()
+    generation := this.generation
+    this.mu.Unlock()
+
+    go func() {
+        if this.prj.LastError() == "" {
+            this.waitTarget(ProxyReadyTimeout)
+        }
+        this.mu.Lock()
+        defer this.mu.Unlock()
+        if this.generation == generation && this.gate != nil {
+            close(this.gate)
+            this.gate = nil
+            if this.reload != nil {
+                this.reload.broadcast("reload")
+            }
+        }
+    }()
+}
+
+// waitTarget 等待项目监听的端口可以连接
+func (this *Proxy) waitTarget(timeout time.Duration) bool {
+    deadline := time.Now().Add(timeout)
+    for {
+        conn, err := net.DialTimeout("tcp", this.target.Host, time.Second)
+        if err == nil {
+            conn.Close()
+            return true
+        }
+        if time.Now().After(deadline) {
+            log.Println("[WARN] 项目", this.prj.name, "在", timeout, "内没有监听", this.target.Host)
+            return false
+        }
+        time.Sleep(100 * time.Millisecond)
+    }
+}
+
+func (this *Proxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+    if this.reload != nil && req.URL.Path == LiveReloadPath {
+        this.reload.ServeHTTP(rw, req)
+        return
+    }
+
+    this.mu.Lock()
+    gate := this.gate
+    this.mu.Unlock()
+    if gate != nil {
+        select {
+        case <-gate:
+        case <-time.After(ProxyHoldTimeout):
+        case <-req.Context().Done():
+            return
+        }
+    }
+
+    if errOutput := this.prj.LastError(); errOutput != "" {
+        var page bytes.Buffer
+        this.prj.newErrorPage(errOutput).render(&page)
+        html := page.Bytes()
+        if this.reload != nil {
+            html = injectScript(html)
+        }
+        rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+        rw.WriteHeader(http.StatusInternalServerError)
+        rw.Write(html)
+        return
+    }
+    this.reverse.ServeHTTP(rw, req)
+}
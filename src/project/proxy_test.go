@@ -0,0 +1,86 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "fmt"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+// serveAsync 在单独的goroutine中通过代理处理一个请求，返回的channel在请求处理完成后收到结果
+func serveAsync(proxy *Proxy) <-chan *httptest.ResponseRecorder {
+    done := make(chan *httptest.ResponseRecorder, 1)
+    go func() {
+        rec := httptest.NewRecorder()
+        proxy.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+        done <- rec
+    }()
+    return done
+}
+
+func TestProxyHoldAndRelease(t *testing.T) {
+    // 项目还没有开始监听的地址
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("Listen failed: %s", err)
+    }
+    addr := listener.Addr().String()
+    listener.Close()
+
+    prj := &Project{name: "proxy"}
+    prj.SetLogFile(LogFileOff, nil)
+    if err = prj.SetProxy("127.0.0.1:0", addr, false); err != nil {
+        t.Fatalf("SetProxy failed: %s", err)
+    }
+    proxy := prj.proxy
+
+    // 第一轮编译结束（Release）后，等待项目监听期间又开始了新一轮编译（Hold）
+    proxy.Hold()
+    held := serveAsync(proxy)
+    proxy.Release()
+    proxy.Hold()
+
+    listener, err = net.Listen("tcp", addr)
+    if err != nil {
+        t.Fatalf("Listen on %s failed: %s", addr, err)
+    }
+    target := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+        fmt.Fprint(rw, "ok")
+    }))
+    target.Listener.Close()
+    target.Listener = listener
+    target.Start()
+    defer target.Close()
+
+    // 第一轮的Release不会放行新一轮编译期间的请求
+    select {
+    case rec := <-held:
+        t.Fatalf("request was released by an older Release: %d %s", rec.Code, rec.Body.String())
+    case <-time.After(500 * time.Millisecond):
+    }
+
+    proxy.Release()
+    select {
+    case rec := <-held:
+        if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+            t.Errorf("held request returned %d %s", rec.Code, rec.Body.String())
+        }
+    case <-time.After(2 * time.Second):
+        t.Fatal("held request was not forwarded after Release")
+    }
+
+    // 最近一次编译失败时返回错误页面
+    prj.lastError = "# web\nsrc/web/main.go:18:1: syntax error: non-declaration statement outside function body"
+    rec := <-serveAsync(proxy)
+    if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Header().Get("Content-Type"), "text/html") ||
+        !strings.Contains(rec.Body.String(), "non-declaration statement outside function body") {
+        t.Errorf("error page was not returned: %d %s", rec.Code, rec.Body.String())
+    }
+}
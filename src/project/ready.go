@@ -0,0 +1,148 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "bytes"
+    "errors"
+    "net"
+    "net/http"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    // DefaultReadyAlive 没有配置就绪检测时，进程启动后仍然存活多久认为启动成功
+    DefaultReadyAlive = 300 * time.Millisecond
+    // DefaultReadyTimeout 就绪检测的默认超时时间
+    DefaultReadyTimeout = 30 * time.Second
+)
+
+// ReadyProbe 项目（deamon）启动后的就绪检测，配置了的检测项都通过才认为启动成功
+type ReadyProbe struct {
+    TCP     string         // 该地址（如:8080）可以建立TCP连接
+    HTTP    string         // 该URL返回2xx
+    Log     *regexp.Regexp // 程序的输出中出现匹配的内容
+    Alive   time.Duration  // 进程启动后至少存活这么久
+    Timeout time.Duration  // 超时时间，超时认为启动失败
+}
+
+// SetReady 设置项目的就绪检测，nil表示使用默认的检测（启动后存活DefaultReadyAlive）。
+// 没有任何检测项（只设置了Timeout）时同样使用默认的检测
+func (this *Project) SetReady(probe *ReadyProbe) {
+    this.ready = probe
+}
+
+// waitReady 等待项目就绪：进程提前退出或者超时都返回error
+func (this *Project) waitReady(started time.Time, exited <-chan struct{}, output *safeBuffer) error {
+    probe := this.ready
+    if probe == nil {
+        probe = &ReadyProbe{Alive: DefaultReadyAlive}
+    } else if probe.empty() {
+        probe = &ReadyProbe{Alive: DefaultReadyAlive, Timeout: probe.Timeout}
+    }
+    timeout := probe.Timeout
+    if timeout <= 0 {
+        timeout = DefaultReadyTimeout
+    }
+    deadline := time.After(timeout)
+    ticker := time.NewTicker(100 * time.Millisecond)
+    defer ticker.Stop()
+    for {
+        // 先检查进程是否已经退出：在Alive期间退出的进程，不能因为Alive时间已过而认为就绪
+        select {
+        case <-exited:
+            return errors.New("进程已退出：" + this.LastExit().String())
+        default:
+        }
+        if probe.ready(started, output) {
+            // 之后的输出只显示在控制台、记录到Logs中
+            output.discard()
+            return nil
+        }
+        select {
+        case <-exited:
+            return errors.New("进程已退出：" + this.LastExit().String())
+        case <-deadline:
+            return errors.New("在" + timeout.String() + "内没有就绪")
+        case <-ticker.C:
+        }
+    }
+}
+
+// empty 是否没有任何检测项（否则会立即认为就绪）
+func (this *ReadyProbe) empty() bool {
+    return this.TCP == "" && this.HTTP == "" && this.Log == nil && this.Alive <= 0
+}
+
+// ready 检测一次是否就绪
+func (this *ReadyProbe) ready(started time.Time, output *safeBuffer) bool {
+    if this.Alive > 0 && time.Since(started) < this.Alive {
+        return false
+    }
+    if this.TCP != "" {
+        address := this.TCP
+        if strings.HasPrefix(address, ":") {
+            address = "127.0.0.1" + address
+        }
+        conn, err := net.DialTimeout("tcp", address, time.Second)
+        if err != nil {
+            return false
+        }
+        conn.Close()
+    }
+    if this.HTTP != "" {
+        client := http.Client{Timeout: 2 * time.Second}
+        resp, err := client.Get(this.HTTP)
+        if err != nil {
+            return false
+        }
+        resp.Body.Close()
+        if resp.StatusCode < 200 || resp.StatusCode > 299 {
+            return false
+        }
+    }
+    if this.Log != nil && !this.Log.Match(output.Bytes()) {
+        return false
+    }
+    return true
+}
+
+// safeBuffer 可以在程序运行过程中并发读取的输出缓存
+type safeBuffer struct {
+    mu        sync.Mutex
+    buf       bytes.Buffer
+    discarded bool
+}
+
+func (this *safeBuffer) Write(p []byte) (int, error) {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    if this.discarded {
+        return len(p), nil
+    }
+    return this.buf.Write(p)
+}
+
+// discard 不再需要缓存的内容：清空，之后的输出也直接丢弃（避免一直运行的程序占用越来越多的内存）
+func (this *safeBuffer) discard() {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    this.discarded = true
+    this.buf = bytes.Buffer{}
+}
+
+// Bytes 返回当前内容的拷贝
+func (this *safeBuffer) Bytes() []byte {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return append([]byte(nil), this.buf.Bytes()...)
+}
+
+func (this *safeBuffer) String() string {
+    return string(this.Bytes())
+}
@@ -0,0 +1,84 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "fmt"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "regexp"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestWaitReady(t *testing.T) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("Listen failed: %s", err)
+    }
+    defer listener.Close()
+    port := listener.Addr().(*net.TCPAddr).Port
+    closed, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("Listen failed: %s", err)
+    }
+    closedPort := closed.Addr().(*net.TCPAddr).Port
+    closed.Close()
+
+    server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+        if req.URL.Path != "/health" {
+            rw.WriteHeader(http.StatusServiceUnavailable)
+        }
+    }))
+    defer server.Close()
+
+    const timeout = 300 * time.Millisecond
+    tests := []struct {
+        name    string
+        probe   *ReadyProbe
+        output  string
+        age     time.Duration // 进程已经启动了多久
+        exited  bool          // 进程是否已经退出
+        err     string        // 期望的错误（包含的内容），为空表示就绪
+        elapsed time.Duration // 至少需要等待的时间
+    }{
+        {"tcp", &ReadyProbe{TCP: fmt.Sprintf(":%d", port)}, "", 0, false, "", 0},
+        {"tcp not listening", &ReadyProbe{TCP: fmt.Sprintf(":%d", closedPort), Timeout: timeout}, "", 0, false, "没有就绪", timeout},
+        {"http", &ReadyProbe{HTTP: server.URL + "/health"}, "", 0, false, "", 0},
+        {"http not 2xx", &ReadyProbe{HTTP: server.URL + "/", Timeout: timeout}, "", 0, false, "没有就绪", timeout},
+        {"log", &ReadyProbe{Log: regexp.MustCompile(`listening on :\d+`)}, "server listening on :8080\n", 0, false, "", 0},
+        {"log not matched", &ReadyProbe{Log: regexp.MustCompile(`listening`), Timeout: timeout}, "starting\n", 0, false, "没有就绪", timeout},
+        {"alive", &ReadyProbe{Alive: 200 * time.Millisecond}, "", 0, false, "", 200 * time.Millisecond},
+        {"default", nil, "", 0, false, "", DefaultReadyAlive},
+        {"empty probe", &ReadyProbe{Timeout: time.Second}, "", 0, false, "", DefaultReadyAlive},
+        {"exited", &ReadyProbe{Alive: 200 * time.Millisecond}, "", 0, true, "进程已退出", 0},
+        {"exited after alive", &ReadyProbe{Alive: 200 * time.Millisecond}, "", time.Second, true, "进程已退出", 0},
+    }
+    for _, test := range tests {
+        prj := &Project{name: "ready"}
+        prj.SetReady(test.probe)
+        exited := make(chan struct{})
+        if test.exited {
+            close(exited)
+        }
+        output := new(safeBuffer)
+        output.Write([]byte(test.output))
+
+        now := time.Now()
+        err := prj.waitReady(now.Add(-test.age), exited, output)
+        elapsed := time.Since(now)
+        if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+            t.Errorf("%s: waitReady returned %v, want %q", test.name, err, test.err)
+        }
+        if elapsed < test.elapsed {
+            t.Errorf("%s: waitReady returned after %s, want at least %s", test.name, elapsed, test.elapsed)
+        }
+        if err == nil && len(output.Bytes()) > 0 {
+            t.Errorf("%s: output was not discarded after ready", test.name)
+        }
+    }
+}
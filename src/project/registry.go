@@ -0,0 +1,165 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "sort"
+    "sync"
+)
+
+// DefaultRegistry autogo监听的所有项目
+var DefaultRegistry = NewRegistry()
+
+// Spec 配置中的一个项目
+type Spec struct {
+    Name       string
+    Definition string                   // 项目配置的规范化表示（如编码后的JSON），相同表示配置没有变化
+    New        func() (*Project, error) // 按配置创建项目（创建后由Registry编译、运行并监听）
+}
+
+// Registry 按名称管理正在监听的项目。配置文件重新加载时，只处理有变化的项目，
+// 避免重复监听、重复启动
+type Registry struct {
+    applyMu sync.Mutex // 同一时间只能有一个Apply
+
+    mu       sync.Mutex
+    projects map[string]*registration
+}
+
+type registration struct {
+    prj        *Project
+    definition string
+}
+
+func NewRegistry() *Registry {
+    return &Registry{projects: make(map[string]*registration)}
+}
+
+// Apply 使正在监听的项目和specs一致：新增的项目按依赖顺序（被依赖的在前）编译、运行并开始监听；配置中删除的项目停止并取消监听；
+// 配置有变化的项目停止后按新配置重新创建；没有变化的项目不受影响。
+// 单个项目出错时记录日志并继续处理其他项目，返回最后一个错误
+func (this *Registry) Apply(specs []Spec) error {
+    this.applyMu.Lock()
+    defer this.applyMu.Unlock()
+
+    var err error
+    wanted := make(map[string]bool, len(specs))
+    for _, spec := range specs {
+        if wanted[spec.Name] {
+            err = errors.New("项目名称重复：" + spec.Name)
+            log.Println("[ERROR]", err)
+            continue
+        }
+        wanted[spec.Name] = true
+    }
+
+    for name, reg := range this.snapshot() {
+        if !wanted[name] {
+            log.Println("[INFO] 项目", name, "已从配置中删除，停止监听")
+            this.remove(name, reg.prj)
+        }
+    }
+
+    var added []*Project
+    applied := make(map[string]bool, len(specs))
+    for _, spec := range specs {
+        if applied[spec.Name] {
+            continue
+        }
+        applied[spec.Name] = true
+        if reg := this.get(spec.Name); reg != nil {
+            if reg.definition == spec.Definition {
+                continue
+            }
+            log.Println("[INFO] 项目", spec.Name, "的配置有变化，重新加载")
+            this.remove(spec.Name, reg.prj)
+        }
+        prj, e := this.add(spec)
+        if e != nil {
+            err = e
+            log.Println("[ERROR] 监控Project：", spec.Name, " 出错。详细信息如下：")
+            fmt.Println(e)
+            continue
+        }
+        added = append(added, prj)
+    }
+
+    // 按依赖顺序编译、运行新增的项目，被依赖的项目先启动
+    for _, prj := range sortByDepends(added) {
+        if e := WatchProject(prj); e != nil {
+            err = e
+            log.Println("[ERROR] 监控Project：", prj.name, " 出错。详细信息如下：")
+            fmt.Println(e)
+        }
+    }
+    return err
+}
+
+// add 创建项目并加入注册表，之后由Apply开始监听（第一次编译出错时仍然在监听，改好后会重新编译）
+func (this *Registry) add(spec Spec) (*Project, error) {
+    prj, err := spec.New()
+    if err != nil {
+        return nil, err
+    }
+    prj.registry = this
+    console.register(spec.Name)
+    this.mu.Lock()
+    this.projects[spec.Name] = &registration{prj: prj, definition: spec.Definition}
+    this.mu.Unlock()
+    return prj, nil
+}
+
+// remove 停止项目并从注册表中删除
+func (this *Registry) remove(name string, prj *Project) {
+    this.mu.Lock()
+    delete(this.projects, name)
+    this.mu.Unlock()
+    if err := prj.Close(); err != nil {
+        log.Println("[WARN] 停止项目", name, "出错：", err)
+    }
+}
+
+func (this *Registry) get(name string) *registration {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return this.projects[name]
+}
+
+func (this *Registry) snapshot() map[string]*registration {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    projects := make(map[string]*registration, len(this.projects))
+    for name, reg := range this.projects {
+        projects[name] = reg
+    }
+    return projects
+}
+
+// Get 返回正在监听的名为name的项目，没有时返回nil
+func (this *Registry) Get(name string) *Project {
+    if reg := this.get(name); reg != nil {
+        return reg.prj
+    }
+    return nil
+}
+
+// Projects 正在监听的所有项目，按名称排序
+func (this *Registry) Projects() []*Project {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    names := make([]string, 0, len(this.projects))
+    for name := range this.projects {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    projects := make([]*Project, len(names))
+    for i, name := range names {
+        projects[i] = this.projects[name].prj
+    }
+    return projects
+}
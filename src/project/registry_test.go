@@ -0,0 +1,117 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "os"
+    "strings"
+    "testing"
+)
+
+func TestRegistryApply(t *testing.T) {
+    const testDir string = "_test"
+
+    if err := os.Mkdir(testDir, 0777); err != nil {
+        t.Fatalf("Failed to create test directory: %s", err)
+    }
+    defer os.RemoveAll(testDir)
+
+    created := make(map[string]int)
+    spec := func(name, definition string) Spec {
+        return Spec{
+            Name:       name,
+            Definition: definition,
+            New: func() (*Project, error) {
+                created[name]++
+                return newTestProject(t, testDir, name, false, false), nil
+            },
+        }
+    }
+
+    registry := NewRegistry()
+    defer registry.Apply(nil)
+
+    if err := registry.Apply([]Spec{spec("a", "1"), spec("b", "1")}); err != nil {
+        t.Fatalf("Apply failed: %s", err)
+    }
+    a, b := registry.Get("a"), registry.Get("b")
+    if a == nil || b == nil {
+        t.Fatalf("projects were not registered: %v", registry.Projects())
+    }
+
+    // a没有变化，b的配置有变化，c是新增的
+    if err := registry.Apply([]Spec{spec("a", "1"), spec("b", "2"), spec("c", "1")}); err != nil {
+        t.Fatalf("Apply failed: %s", err)
+    }
+    if registry.Get("a") != a {
+        t.Error("unchanged project a was recreated")
+    }
+    if registry.Get("b") == b {
+        t.Error("changed project b was not recreated")
+    }
+    if !b.closed {
+        t.Error("old project b was not closed")
+    }
+    if created["a"] != 1 || created["b"] != 2 || created["c"] != 1 {
+        t.Errorf("unexpected creations: %v", created)
+    }
+
+    // 删除a
+    if err := registry.Apply([]Spec{spec("b", "2"), spec("c", "1")}); err != nil {
+        t.Fatalf("Apply failed: %s", err)
+    }
+    if registry.Get("a") != nil || !a.closed {
+        t.Error("removed project a is still watched")
+    }
+    projects := registry.Projects()
+    if len(projects) != 2 || projects[0].name != "b" || projects[1].name != "c" {
+        t.Errorf("unexpected projects: %v", projects)
+    }
+
+    // 名称重复
+    if err := registry.Apply([]Spec{spec("b", "2"), spec("c", "1"), spec("c", "2")}); err == nil {
+        t.Error("duplicate project names should be reported")
+    }
+    if created["c"] != 1 {
+        t.Errorf("duplicate project c was applied: %v", created)
+    }
+}
+
+func TestDependents(t *testing.T) {
+    // svc依赖app，app依赖lib（目录都不存在，GOPATH项目的源码目录就是根目录）
+    lib := &Project{name: "lib", Root: "/autogo/lib", srcAbsolutePath: "/autogo/lib"}
+    app := &Project{name: "app", Root: "/autogo/app", srcAbsolutePath: "/autogo/app", Depends: []string{"../lib"}}
+    svc := &Project{name: "svc", Root: "/autogo/svc", srcAbsolutePath: "/autogo/svc", Depends: []string{"/autogo/app"}}
+    other := &Project{name: "other", Root: "/autogo/other", srcAbsolutePath: "/autogo/other"}
+
+    names := func(projects []*Project) string {
+        var names []string
+        for _, prj := range projects {
+            names = append(names, prj.name)
+        }
+        return strings.Join(names, ",")
+    }
+    if sorted := names(sortByDepends([]*Project{svc, other, app, lib})); sorted != "other,lib,app,svc" {
+        t.Errorf("sortByDepends returned %s", sorted)
+    }
+
+    registry := NewRegistry()
+    for _, prj := range []*Project{svc, app, lib, other} {
+        registry.projects[prj.name] = &registration{prj: prj}
+        prj.registry = registry
+    }
+    if dependents := names(registry.Dependents(lib)); dependents != "app,svc" {
+        t.Errorf("Dependents(lib) returned %s", dependents)
+    }
+    if dependents := names(registry.Dependents(svc)); dependents != "" {
+        t.Errorf("Dependents(svc) returned %s", dependents)
+    }
+    if prj := app.dependProject("/autogo/lib/util/util.go"); prj != lib {
+        t.Errorf("dependProject returned %v", prj)
+    }
+    if prj := app.dependProject("/autogo/app/main.go"); prj != nil {
+        t.Errorf("dependProject returned %v for the project's own file", prj)
+    }
+}
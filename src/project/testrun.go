@@ -0,0 +1,330 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "html/template"
+    "io"
+    "io/ioutil"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+)
+
+var (
+    testTplFile = "templates/test.html"
+
+    testTpl     *template.Template
+    testTplOnce sync.Once
+
+    // coverageRe 匹配go test -cover输出的覆盖率
+    coverageRe = regexp.MustCompile(`coverage: ([\d.]+%) of statements`)
+)
+
+// testResultsTpl 测试结果页面模板，第一次使用时解析（模板路径相对于autogo的工作目录）
+func testResultsTpl() *template.Template {
+    testTplOnce.Do(func() {
+        testTpl = template.Must(template.ParseFiles(testTplFile))
+    })
+    return testTpl
+}
+
+// TestReport 一次go test的结果
+type TestReport struct {
+    Project     string           `json:"project"`
+    Started     time.Time        `json:"started"`
+    Duration    time.Duration    `json:"duration"`
+    Packages    []*PackageResult `json:"packages"`
+    BuildOutput string           `json:"build_output,omitempty"` // 编译测试时的错误输出
+}
+
+// PackageResult 一个包的测试结果
+type PackageResult struct {
+    Package  string        `json:"package"`
+    Action   string        `json:"action"` // pass、fail，或者skip（没有测试文件）
+    Elapsed  time.Duration `json:"elapsed"`
+    Coverage string        `json:"coverage,omitempty"` // 如85.0%
+    Output   string        `json:"output,omitempty"`   // 不属于某个测试的输出
+    Tests    []*TestResult `json:"tests,omitempty"`
+}
+
+// TestResult 一个测试（包括子测试，如TestA/sub）的结果
+type TestResult struct {
+    Name    string        `json:"name"`
+    Action  string        `json:"action"` // pass、fail或skip
+    Elapsed time.Duration `json:"elapsed"`
+    Output  string        `json:"output,omitempty"`
+}
+
+// testEvent go test -json输出的一个事件（见go doc test2json）
+type testEvent struct {
+    Action     string
+    Package    string
+    Test       string
+    Elapsed    float64
+    Output     string
+    ImportPath string // build-output事件（编译测试的输出）
+}
+
+// ParseTestEvents 解析go test -json的输出，包、测试按第一次出现的顺序排列
+func ParseTestEvents(r io.Reader) (*TestReport, error) {
+    report := new(TestReport)
+    packages := make(map[string]*PackageResult)
+    tests := make(map[string]*TestResult)
+    var buildOutput []string
+    decoder := json.NewDecoder(r)
+    for {
+        var event testEvent
+        if err := decoder.Decode(&event); err == io.EOF {
+            break
+        } else if err != nil {
+            return nil, err
+        }
+        if event.Action == "build-output" {
+            buildOutput = append(buildOutput, event.Output)
+            continue
+        }
+        if event.Package == "" {
+            continue
+        }
+        pkg := packages[event.Package]
+        if pkg == nil {
+            pkg = &PackageResult{Package: event.Package}
+            packages[event.Package] = pkg
+            report.Packages = append(report.Packages, pkg)
+        }
+        if event.Test == "" {
+            switch event.Action {
+            case "output":
+                pkg.Output += event.Output
+                if matches := coverageRe.FindStringSubmatch(event.Output); matches != nil {
+                    pkg.Coverage = matches[1]
+                }
+            case "pass", "fail", "skip":
+                pkg.Action = event.Action
+                pkg.Elapsed = seconds(event.Elapsed)
+            }
+            continue
+        }
+        key := event.Package + " " + event.Test
+        test := tests[key]
+        if test == nil {
+            test = &TestResult{Name: event.Test}
+            tests[key] = test
+            pkg.Tests = append(pkg.Tests, test)
+        }
+        switch event.Action {
+        case "output":
+            if !isTestStatusLine(event.Output) {
+                test.Output += event.Output
+            }
+        case "pass", "fail", "skip":
+            test.Action = event.Action
+            test.Elapsed = seconds(event.Elapsed)
+        }
+    }
+    report.BuildOutput = strings.TrimSpace(strings.Join(buildOutput, ""))
+    return report, nil
+}
+
+// isTestStatusLine go test输出中的=== RUN、--- FAIL等状态行（结果已经单独记录）
+func isTestStatusLine(line string) bool {
+    line = strings.TrimSpace(line)
+    for _, prefix := range []string{"=== RUN", "=== PAUSE", "=== CONT", "=== NAME", "--- PASS", "--- FAIL", "--- SKIP"} {
+        if strings.HasPrefix(line, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+// seconds test2json中的耗时（秒）
+func seconds(elapsed float64) time.Duration {
+    return time.Duration(elapsed * float64(time.Second))
+}
+
+// Count 通过、失败、跳过的测试数（有子测试的测试只统计其子测试）。包中的测试没有执行（如编译失败）时，该包算一个失败
+func (this *TestReport) Count() (passed, failed, skipped int) {
+    for _, pkg := range this.Packages {
+        if pkg.Action == "fail" && len(pkg.Tests) == 0 {
+            failed++
+        }
+        for i, test := range pkg.Tests {
+            if i+1 < len(pkg.Tests) && strings.HasPrefix(pkg.Tests[i+1].Name, test.Name+"/") {
+                continue
+            }
+            switch test.Action {
+            case "pass":
+                passed++
+            case "fail":
+                failed++
+            case "skip":
+                skipped++
+            }
+        }
+    }
+    return
+}
+
+// Passed 是否所有测试都通过
+func (this *TestReport) Passed() bool {
+    if this.BuildOutput != "" {
+        return false
+    }
+    for _, pkg := range this.Packages {
+        if pkg.Action == "fail" {
+            return false
+        }
+    }
+    return true
+}
+
+// Summary 一句话的测试结果
+func (this *TestReport) Summary() string {
+    passed, failed, skipped := this.Count()
+    return fmt.Sprintf("通过%d个，失败%d个，跳过%d个，耗时%s", passed, failed, skipped, this.Duration.Round(time.Millisecond))
+}
+
+// SetTestOnChange 源码改动（重新编译、启动成功）后是否运行测试；go_way为test时总是运行测试
+func (this *Project) SetTestOnChange(testOnChange bool) {
+    this.testOnChange = testOnChange
+}
+
+// testing 源码改动时是否需要运行测试
+func (this *Project) testing() bool {
+    return this.GoWay == "test" || this.testOnChange
+}
+
+// LastTestReport 最近一次测试的结果，没有运行过测试时为nil
+func (this *Project) LastTestReport() *TestReport {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return this.lastTest
+}
+
+// Test 运行项目的测试：changed是改动的文件（绝对路径），只测试这些文件所在的包以及依赖它们的包；
+// 没有changed时测试所有的包。结果输出到控制台，并写入_log_/test-results.html和test-results.json
+func (this *Project) Test(changed ...string) error {
+    affected, err := this.Affected(changed...)
+    if err != nil {
+        return err
+    }
+    return this.runTests(affected.TestPackages())
+}
+
+// runTests 运行某些包的测试
+func (this *Project) runTests(packages []string) error {
+    if len(packages) == 0 {
+        log.Println("[INFO] 项目", this.name, "没有需要运行的测试")
+        return nil
+    }
+    started := time.Now()
+    result, err := DefaultBuilder.run(DefaultBuilder.TestCommand(this, packages))
+    if err != nil {
+        return err
+    }
+    report, err := ParseTestEvents(strings.NewReader(result.Stdout))
+    if err != nil {
+        return errors.New("解析go test的输出出错：" + err.Error())
+    }
+    report.Project, report.Started, report.Duration = this.name, started, time.Since(started)
+    if stderr := strings.TrimSpace(result.Stderr); stderr != "" {
+        report.BuildOutput = strings.TrimSpace(report.BuildOutput + "\n" + stderr)
+    }
+
+    this.mu.Lock()
+    this.lastTest = report
+    if this.GoWay == "test" {
+        this.buildTime = report.Duration
+    }
+    this.mu.Unlock()
+    report.print()
+    if err = this.writeTestReport(report); err != nil {
+        log.Println("can't write test results: ", err)
+    }
+    if report.Passed() {
+        return nil
+    }
+    if this.GoWay == "test" {
+        if report.BuildOutput != "" {
+            // 测试编译失败，像编译错误一样写入错误页面
+            return this.writeError(report.BuildOutput)
+        }
+        // 测试编译通过，之前的编译错误已经不存在了（失败的测试见测试结果）
+        this.clearError()
+    }
+    return errors.New("测试失败：" + report.Summary())
+}
+
+// retest go_way为test时代替编译、运行：执行pre_build命令后运行测试
+func (this *Project) retest(changed []string) error {
+    if err := this.runHooks("pre_build"); err != nil {
+        return err
+    }
+    if err := this.Test(changed...); err != nil {
+        return err
+    }
+    this.clearError()
+    return nil
+}
+
+// print 在控制台输出测试结果（类似go test，失败的测试附带其输出）
+func (this *TestReport) print() {
+    log.Println("=====================")
+    log.Println("[INFO] 项目", this.Project, "的测试结果:")
+    if this.BuildOutput != "" {
+        fmt.Println(this.BuildOutput)
+    }
+    for _, pkg := range this.Packages {
+        status := map[string]string{"pass": "ok  ", "fail": "FAIL", "skip": "?   "}[pkg.Action]
+        line := fmt.Sprintf("%s\t%s\t%.3fs", status, pkg.Package, pkg.Elapsed.Seconds())
+        if pkg.Coverage != "" {
+            line += "\tcoverage: " + pkg.Coverage
+        }
+        fmt.Println(line)
+        for _, test := range pkg.Tests {
+            if test.Action != "fail" {
+                continue
+            }
+            fmt.Printf("    --- FAIL: %s (%.2fs)\n", test.Name, test.Elapsed.Seconds())
+            for _, output := range strings.Split(strings.TrimRight(test.Output, "\n"), "\n") {
+                fmt.Println("    " + output)
+            }
+        }
+    }
+    if this.Passed() {
+        log.Println("[INFO] 项目", this.Project, "测试通过：", this.Summary())
+    } else {
+        log.Println("[ERROR] 项目", this.Project, "测试失败：", this.Summary())
+    }
+    log.Println("=====================")
+}
+
+// writeTestReport 将测试结果写入_log_/test-results.html（templates/test.html）和test-results.json
+func (this *Project) writeTestReport(report *TestReport) error {
+    if err := os.MkdirAll(this.errAbsolutePath, 0777); err != nil {
+        return err
+    }
+    file, err := os.Create(filepath.Join(this.errAbsolutePath, "test-results.html"))
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+    if err = testResultsTpl().Execute(file, report); err != nil {
+        return err
+    }
+    content, err := json.MarshalIndent(report, "", "  ")
+    if err != nil {
+        return err
+    }
+    return ioutil.WriteFile(filepath.Join(this.errAbsolutePath, "test-results.json"), content, 0666)
+}
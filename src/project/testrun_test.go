@@ -0,0 +1,98 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestParseTestEvents(t *testing.T) {
+    report, err := ParseTestEvents(strings.NewReader(`{"Action":"start","Package":"app/a"}
+{"Action":"run","Package":"app/a","Test":"TestAdd"}
+{"Action":"output","Package":"app/a","Test":"TestAdd","Output":"=== RUN   TestAdd\n"}
+{"Action":"run","Package":"app/a","Test":"TestAdd/ok"}
+{"Action":"pass","Package":"app/a","Test":"TestAdd/ok","Elapsed":0}
+{"Action":"run","Package":"app/a","Test":"TestAdd/bad"}
+{"Action":"output","Package":"app/a","Test":"TestAdd/bad","Output":"    a_test.go:7: 1+1 != 3\n"}
+{"Action":"output","Package":"app/a","Test":"TestAdd/bad","Output":"--- FAIL: TestAdd/bad (0.00s)\n"}
+{"Action":"fail","Package":"app/a","Test":"TestAdd/bad","Elapsed":0.01}
+{"Action":"fail","Package":"app/a","Test":"TestAdd","Elapsed":0.01}
+{"Action":"skip","Package":"app/a","Test":"TestSkip","Elapsed":0}
+{"Action":"output","Package":"app/a","Output":"coverage: 62.5% of statements\n"}
+{"Action":"fail","Package":"app/a","Elapsed":0.5}
+{"Action":"output","Package":"app/c","Output":"?   \tapp/c\t[no test files]\n"}
+{"Action":"skip","Package":"app/c","Elapsed":0}
+{"ImportPath":"app/b [app/b.test]","Action":"build-output","Output":"# app/b [app/b.test]\n"}
+{"ImportPath":"app/b [app/b.test]","Action":"build-output","Output":"b/b_test.go:5:28: undefined: undefined\n"}
+{"ImportPath":"app/b [app/b.test]","Action":"build-fail"}
+{"Action":"output","Package":"app/b","Output":"FAIL\tapp/b [build failed]\n"}
+{"Action":"fail","Package":"app/b","Elapsed":0}
+`))
+    if err != nil {
+        t.Fatalf("ParseTestEvents failed: %s", err)
+    }
+    if len(report.Packages) != 3 {
+        t.Fatalf("unexpected packages: %+v", report.Packages)
+    }
+    a := report.Packages[0]
+    if a.Package != "app/a" || a.Action != "fail" || a.Coverage != "62.5%" || a.Elapsed.Seconds() != 0.5 || len(a.Tests) != 4 {
+        t.Errorf("unexpected result of app/a: %+v", a)
+    }
+    if bad := a.Tests[2]; bad.Name != "TestAdd/bad" || bad.Action != "fail" || bad.Output != "    a_test.go:7: 1+1 != 3\n" {
+        t.Errorf("unexpected result of TestAdd/bad: %+v", bad)
+    }
+    if c := report.Packages[1]; c.Package != "app/c" || c.Action != "skip" {
+        t.Errorf("unexpected result of app/c: %+v", c)
+    }
+    if report.BuildOutput != "# app/b [app/b.test]\nb/b_test.go:5:28: undefined: undefined" {
+        t.Errorf("unexpected build output: %q", report.BuildOutput)
+    }
+    // TestAdd只统计其子测试，app/b编译失败算一个失败
+    if passed, failed, skipped := report.Count(); passed != 1 || failed != 2 || skipped != 1 {
+        t.Errorf("Count() returned %d, %d, %d", passed, failed, skipped)
+    }
+    if report.Passed() {
+        t.Error("Passed() should be false")
+    }
+}
+
+func TestRetestClearsError(t *testing.T) {
+    dir, err := ioutil.TempDir("", "autogo")
+    if err != nil {
+        t.Fatalf("TempDir failed: %s", err)
+    }
+    defer os.RemoveAll(dir)
+
+    root := filepath.Join(dir, "calc")
+    writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/calc\n\ngo 1.16\n")
+    writeTestFile(t, filepath.Join(root, "calc.go"), "package calc\n\nfunc Add(a, b int) int {\n    return a - b\n}\n")
+    testFile := filepath.Join(root, "calc_test.go")
+    writeTestFile(t, testFile, "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n    if Add(1, 2) != 3 {\n        t.Error(Sub(1, 2))\n    }\n}\n")
+    prj, err := New("calc", root, "test", "", false)
+    if err != nil {
+        t.Fatalf("New failed: %s", err)
+    }
+    prj.SetLogFile(LogFileOff, nil)
+
+    // 测试编译失败时和编译错误一样
+    if err = prj.retest(nil); err == nil || !strings.Contains(prj.LastError(), "undefined: Sub") {
+        t.Fatalf("retest returned %v, LastError is %q", err, prj.LastError())
+    }
+    // 测试编译通过但没有通过时，不再保留之前的编译错误
+    writeTestFile(t, testFile, "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n    if Add(1, 2) != 3 {\n        t.Error(Add(1, 2))\n    }\n}\n")
+    if err = prj.retest(nil); err == nil || !strings.Contains(err.Error(), "测试失败") {
+        t.Fatalf("retest returned %v", err)
+    }
+    if prj.LastError() != "" {
+        t.Errorf("LastError is still %q", prj.LastError())
+    }
+    if _, err = os.Stat(filepath.Join(prj.errAbsolutePath, "error.html")); !os.IsNotExist(err) {
+        t.Errorf("error.html was not removed: %v", err)
+    }
+}
@@ -0,0 +1,54 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "fmt"
+    "log"
+)
+
+// SetVetOnChange 源码改动后是否对改动的包执行go vet（发现的问题只作为警告输出，不影响编译、运行）
+func (this *Project) SetVetOnChange(vetOnChange bool) {
+    this.vetOnChange = vetOnChange
+}
+
+// Vet 对项目中的某些包（import path）执行go vet，返回发现的问题
+func (this *Project) Vet(packages ...string) ([]Diagnostic, error) {
+    if len(packages) == 0 {
+        return nil, nil
+    }
+    if err := this.writeModFile(); err != nil {
+        return nil, err
+    }
+    result, err := DefaultBuilder.run(DefaultBuilder.VetCommand(this, packages))
+    if err != nil {
+        return nil, err
+    }
+    if result.Success() {
+        return nil, nil
+    }
+    return this.diagnose(result.Output()), nil
+}
+
+// vet 执行go vet并在控制台输出发现的问题
+func (this *Project) vet(packages []string) {
+    diagnostics, err := this.Vet(packages...)
+    if err != nil {
+        log.Println("[WARN] 项目", this.name, "执行go vet出错：", err)
+        return
+    }
+    if len(diagnostics) == 0 {
+        log.Println("[INFO] 项目", this.name, "go vet没有发现问题：", packages)
+        return
+    }
+    log.Println("[WARN] 项目", this.name, "go vet发现", len(diagnostics), "个问题：")
+    for _, diagnostic := range diagnostics {
+        if diagnostic.File == "" {
+            fmt.Println("    " + diagnostic.Message)
+            continue
+        }
+        fmt.Printf("    %s:%d:%d: %s\n", diagnostic.File, diagnostic.Line, diagnostic.Column, diagnostic.Message)
+    }
+}
@@ -0,0 +1,150 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "path"
+    "path/filepath"
+    "strings"
+)
+
+var (
+    // DefaultWatchInclude 默认只有Go源文件的改动才会触发重新编译
+    DefaultWatchInclude = []string{"**/*.go"}
+    // DefaultWatchExclude 默认排除测试文件、编辑器的临时文件以及vendor、testdata目录
+    DefaultWatchExclude = []string{"*_test.go", ".#*", "*.swp", "vendor/", "testdata/"}
+)
+
+// WatchFilter 决定哪些文件的改动会触发重新编译。规则是相对于监听目录（src或Go Module项目的根目录）、
+// 用/分隔的glob。不包含/的规则匹配文件名（任意目录中），如*.swp；以/结尾的规则匹配目录（任意层级），
+// 该目录中的所有文件都匹配，且排除时该目录不会被监听，如vendor/；其他规则匹配整个相对路径，
+// **匹配任意多层目录，如**/*.go、web/static/**。
+type WatchFilter struct {
+    Include []string
+    Exclude []string
+}
+
+// SetWatchFilter 设置项目的监听规则，include或exclude为nil时使用默认规则
+func (this *Project) SetWatchFilter(include, exclude []string) {
+    if include == nil {
+        include = DefaultWatchInclude
+    }
+    if exclude == nil {
+        exclude = DefaultWatchExclude
+    }
+    this.watchFilter = &WatchFilter{Include: include, Exclude: exclude}
+}
+
+// filter 项目的监听规则，没有设置时使用默认规则
+func (this *Project) filter() *WatchFilter {
+    if this.watchFilter == nil {
+        return &WatchFilter{Include: DefaultWatchInclude, Exclude: DefaultWatchExclude}
+    }
+    return this.watchFilter
+}
+
+// relPath 文件相对于所在监听目录（src目录或者depends的源码目录）的路径
+func (this *Project) relPath(name string) (string, bool) {
+    _, rel, ok := this.watchRoot(name)
+    return rel, ok
+}
+
+// skipWatch 监听时是否忽略该目录：被监听规则排除的目录；Go Module项目（以及依赖的Go Module）监听的是整个根目录，
+// 还需要排除编译生成的bin、错误信息目录_log_以及隐藏目录（如.git），否则会不断触发重新编译
+func (this *Project) skipWatch(dir string) bool {
+    root, rel, ok := this.watchRoot(dir)
+    if !ok || rel == "." {
+        return false
+    }
+    if this.filter().SkipDir(rel) {
+        return true
+    }
+    if root == this.srcAbsolutePath && !this.module || root != this.srcAbsolutePath && !isModuleRoot(root) {
+        return false
+    }
+    if rel == "bin" || rel == "_log_" {
+        return true
+    }
+    return strings.HasPrefix(filepath.Base(dir), ".")
+}
+
+// Match 判断文件（相对路径）的改动是否需要触发重新编译
+func (this *WatchFilter) Match(name string) bool {
+    name = filepath.ToSlash(name)
+    return matchAny(this.Include, name, false) && !matchAny(this.Exclude, name, false)
+}
+
+// SkipDir 判断目录（相对路径）是否被排除，被排除的目录不需要监听
+func (this *WatchFilter) SkipDir(dir string) bool {
+    return matchAny(this.Exclude, filepath.ToSlash(dir), true)
+}
+
+// matchAny 是否有规则匹配name；isDir表示name本身是目录
+func matchAny(patterns []string, name string, isDir bool) bool {
+    for _, pattern := range patterns {
+        if matchGlob(pattern, name, isDir) {
+            return true
+        }
+    }
+    return false
+}
+
+// matchGlob 按WatchFilter中描述的规则匹配
+func matchGlob(pattern, name string, isDir bool) bool {
+    segments := strings.Split(name, "/")
+    if strings.HasSuffix(pattern, "/") {
+        // 目录规则：匹配name中的某一级目录（name本身是目录时包括最后一级）
+        dirs := segments[:len(segments)-1]
+        if isDir {
+            dirs = segments
+        }
+        pattern = strings.TrimSuffix(pattern, "/")
+        if strings.Contains(pattern, "/") {
+            patterns := strings.Split(pattern, "/")
+            for i := 0; i <= len(dirs); i++ {
+                if matchSegments(patterns, dirs[:i]) {
+                    return true
+                }
+            }
+            return false
+        }
+        for _, dir := range dirs {
+            if ok, _ := path.Match(pattern, dir); ok {
+                return true
+            }
+        }
+        return false
+    }
+    if isDir {
+        return false
+    }
+    if !strings.Contains(pattern, "/") {
+        ok, _ := path.Match(pattern, segments[len(segments)-1])
+        return ok
+    }
+    return matchSegments(strings.Split(pattern, "/"), segments)
+}
+
+// matchSegments 逐级匹配，**匹配任意多级（包括0级）
+func matchSegments(patterns, segments []string) bool {
+    if len(patterns) == 0 {
+        return len(segments) == 0
+    }
+    if patterns[0] == "**" {
+        for i := 0; i <= len(segments); i++ {
+            if matchSegments(patterns[1:], segments[i:]) {
+                return true
+            }
+        }
+        return false
+    }
+    if len(segments) == 0 {
+        return false
+    }
+    if ok, _ := path.Match(patterns[0], segments[0]); !ok {
+        return false
+    }
+    return matchSegments(patterns[1:], segments[1:])
+}
@@ -0,0 +1,52 @@
+// Copyright 2012 polaris(studygolang.com). All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+    "testing"
+)
+
+func TestWatchFilterMatch(t *testing.T) {
+    filter := &WatchFilter{Include: DefaultWatchInclude, Exclude: DefaultWatchExclude}
+    tests := []struct {
+        name  string
+        match bool
+    }{
+        {"main.go", true},
+        {"cmd/app/main.go", true},
+        {"cmd/app/main_test.go", false},
+        {"cmd/app/.#main.go", false},
+        {"cmd/app/.main.go.swp", false},
+        {"vendor/github.com/x/y.go", false},
+        {"pkg/testdata/fixture.go", false},
+        {"web/static/app.js", false},
+        {"_log_/error.html", false},
+    }
+    for _, test := range tests {
+        if match := filter.Match(test.name); match != test.match {
+            t.Errorf("Match(%q) = %v, want %v", test.name, match, test.match)
+        }
+    }
+
+    filter = &WatchFilter{Include: []string{"**/*.go", "templates/**"}, Exclude: []string{"web/static/", "gen/*.go"}}
+    tests = []struct {
+        name  string
+        match bool
+    }{
+        {"templates/index.html", true},
+        {"templates/admin/list.html", true},
+        {"web/static/app.go", false},
+        {"gen/models.go", false},
+        {"gen/sub/models.go", true},
+    }
+    for _, test := range tests {
+        if match := filter.Match(test.name); match != test.match {
+            t.Errorf("Match(%q) = %v, want %v", test.name, match, test.match)
+        }
+    }
+    if !filter.SkipDir("web/static") || filter.SkipDir("web") {
+        t.Errorf("SkipDir: web/static should be skipped, web should not")
+    }
+}
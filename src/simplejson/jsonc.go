@@ -0,0 +1,156 @@
+package simplejson
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "unicode/utf8"
+)
+
+// SyntaxError 带注释的JSON（JSONC）的语法错误，Line和Column是原始内容中的位置（从1开始，Column按字符计算）
+type SyntaxError struct {
+    Filename string
+    Line     int
+    Column   int
+    Msg      string
+}
+
+func (e *SyntaxError) Error() string {
+    if e.Filename == "" {
+        return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+    }
+    return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Msg)
+}
+
+// NewJsonc 解析带注释的JSON：支持//行注释、/* */块注释以及对象、数组最后多余的逗号
+func NewJsonc(body []byte) (*Json, error) {
+    return parseJsonc("", body)
+}
+
+func parseJsonc(filename string, body []byte) (*Json, error) {
+    stripped, err := StripComments(body)
+    if err != nil {
+        if e, ok := err.(*SyntaxError); ok {
+            e.Filename = filename
+        }
+        return nil, err
+    }
+    j, err := NewJson(stripped)
+    if err == nil {
+        return j, nil
+    }
+    // StripComments不改变内容的长度，json报告的偏移量就是原始内容中的偏移量
+    // （json报告的是读到出错的字符之后的偏移量）
+    var offset int64
+    switch e := err.(type) {
+    case *json.SyntaxError:
+        offset = e.Offset
+    case *json.UnmarshalTypeError:
+        offset = e.Offset
+    default:
+        return nil, err
+    }
+    if offset > 0 {
+        offset--
+    }
+    return nil, newSyntaxError(filename, body, int(offset), err.Error())
+}
+
+// StripComments 将JSONC转为标准JSON：注释和多余的逗号替换为空格（保留换行），字符串的内容不受影响。
+// 返回的内容和body长度相同，位置一一对应
+func StripComments(body []byte) ([]byte, error) {
+    out := make([]byte, len(body))
+    copy(out, body)
+    var commas []int // 字符串外的逗号
+    for i := 0; i < len(out); i++ {
+        switch out[i] {
+        case '"':
+            i = skipString(out, i)
+        case ',':
+            commas = append(commas, i)
+        case '/':
+            if i+1 >= len(out) {
+                continue
+            }
+            switch out[i+1] {
+            case '/':
+                for ; i < len(out) && out[i] != '\n'; i++ {
+                    blank(out, i)
+                }
+            case '*':
+                end := bytes.Index(out[i+2:], []byte("*/"))
+                if end < 0 {
+                    return nil, newSyntaxError("", body, i, "unterminated block comment")
+                }
+                end += i + 2 + 2
+                for ; i < end; i++ {
+                    blank(out, i)
+                }
+                i--
+            }
+        }
+    }
+    // 只去掉值（或者}、]）之后的逗号，[,]、{,}这种仍然是错误的
+    for _, comma := range commas {
+        next, prev := nextToken(out, comma+1), prevToken(out, comma-1)
+        if next < len(out) && (out[next] == '}' || out[next] == ']') && prev >= 0 && !bytes.ContainsRune([]byte("[{,:"), rune(out[prev])) {
+            out[comma] = ' '
+        }
+    }
+    return out, nil
+}
+
+// skipString 跳过从start（"）开始的字符串，返回结束的"的位置；字符串没有结束时返回最后的位置
+func skipString(data []byte, start int) int {
+    for i := start + 1; i < len(data); i++ {
+        switch data[i] {
+        case '\\':
+            i++
+        case '"', '\n':
+            return i
+        }
+    }
+    return len(data) - 1
+}
+
+// blank 将注释中的字符替换为空格，保留换行
+func blank(data []byte, i int) {
+    if data[i] != '\n' && data[i] != '\r' {
+        data[i] = ' '
+    }
+}
+
+// nextToken 从start开始第一个非空白字符的位置
+func nextToken(data []byte, start int) int {
+    for i := start; i < len(data); i++ {
+        switch data[i] {
+        case ' ', '\t', '\r', '\n':
+        default:
+            return i
+        }
+    }
+    return len(data)
+}
+
+// prevToken 从start往前第一个非空白字符的位置，没有时返回-1
+func prevToken(data []byte, start int) int {
+    for i := start; i >= 0; i-- {
+        switch data[i] {
+        case ' ', '\t', '\r', '\n':
+        default:
+            return i
+        }
+    }
+    return -1
+}
+
+// newSyntaxError 根据body中的偏移量计算行号和列号
+func newSyntaxError(filename string, body []byte, offset int, msg string) *SyntaxError {
+    if offset > len(body) {
+        offset = len(body)
+    }
+    line := bytes.Count(body[:offset], []byte("\n")) + 1
+    lineStart := bytes.LastIndexByte(body[:offset], '\n') + 1
+    column := utf8.RuneCount(body[lineStart:offset]) + 1
+    return &SyntaxError{Filename: filename, Line: line, Column: column, Msg: msg}
+}
@@ -0,0 +1,78 @@
+package simplejson
+
+import (
+    "github.com/bmizerany/assert"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestJsonc(t *testing.T) {
+    js, err := NewJsonc([]byte(`// 项目列表
+[
+    /* 第一个项目
+       注释可以有多行 */
+    {
+        "name": "test", // 行尾注释
+        "root": "../test", /* 行内注释 */
+        "url": "http://localhost:8080/a//b",
+        "comment": "/* 不是注释 */ \" // 也不是",
+        "depends": ["a", "b",],
+    },
+]`))
+    assert.Equal(t, nil, err)
+
+    prj := js.GetIndex(0)
+    assert.Equal(t, "test", prj.Get("name").MustString())
+    assert.Equal(t, "../test", prj.Get("root").MustString())
+    assert.Equal(t, "http://localhost:8080/a//b", prj.Get("url").MustString())
+    assert.Equal(t, "/* 不是注释 */ \" // 也不是", prj.Get("comment").MustString())
+    assert.Equal(t, []string{"a", "b"}, prj.GetStringSlice("depends"))
+
+    arr, err := js.Array()
+    assert.Equal(t, nil, err)
+    assert.Equal(t, 1, len(arr))
+}
+
+func TestJsoncStripKeepsOffsets(t *testing.T) {
+    body := []byte("{\"a\": 1, // 注释\n/* x\ny */ \"b\": [2,],}")
+    stripped, err := StripComments(body)
+    assert.Equal(t, nil, err)
+    assert.Equal(t, len(body), len(stripped))
+    // 注释的每个字节（包括中文）都替换为空格
+    expected := "{\"a\": 1," + strings.Repeat(" ", len(" // 注释")) + "\n    \n     \"b\": [2 ] }"
+    assert.Equal(t, expected, string(stripped))
+}
+
+func TestJsoncErrors(t *testing.T) {
+    // 错误的位置是原始内容中的行号和列号（注释不影响）
+    _, err := NewJsonc([]byte("[\n    // 注释\n    {\"name\": \"test\" \"root\": \"x\"}\n]"))
+    e, ok := err.(*SyntaxError)
+    assert.Equal(t, true, ok)
+    assert.Equal(t, 3, e.Line)
+    assert.Equal(t, 21, e.Column)
+
+    _, err = NewJsonc([]byte("{\n  \"a\": 1 /* 没有结束"))
+    e, ok = err.(*SyntaxError)
+    assert.Equal(t, true, ok)
+    assert.Equal(t, 2, e.Line)
+    assert.Equal(t, 10, e.Column)
+
+    // 逗号之前没有值时不能去掉
+    for _, body := range []string{"[,]", "{,}", "[1,,]", "{\"a\": ,}", "[ /* 注释 */ , ]"} {
+        _, err = NewJsonc([]byte(body))
+        _, ok = err.(*SyntaxError)
+        assert.Equal(t, true, ok, body, err)
+    }
+
+    dir, err := ioutil.TempDir("", "simplejson")
+    assert.Equal(t, nil, err)
+    defer os.RemoveAll(dir)
+    filename := filepath.Join(dir, "projects.json")
+    ioutil.WriteFile(filename, []byte("[\n  {\"name\": }\n]"), 0666)
+    _, err = ParseFile(filename)
+    assert.NotEqual(t, nil, err)
+    assert.Equal(t, filename+":2:12: invalid character '}' looking for beginning of value", err.Error())
+}
@@ -1,12 +1,10 @@
 package simplejson
 
 import (
-    "bytes"
     "encoding/json"
     "errors"
     "io/ioutil"
     "log"
-    "strings"
 )
 
 // returns the current implementation version
@@ -18,23 +16,13 @@ type Json struct {
     data interface{}
 }
 
-// 解析json文件，过滤掉注释（只支持注释在单独一行，以"//"注释）
+// 解析json文件，支持注释（//和/* */）以及最后多余的逗号，出错时报告文件中的行号和列号
 func ParseFile(filename string) (*Json, error) {
     stream, err := ioutil.ReadFile(filename)
     if err != nil {
         return nil, err
     }
-    content := string(stream)
-    var builder bytes.Buffer
-    lines := strings.Split(content, "\n")
-    for _, line := range lines {
-        line = strings.TrimSpace(line)
-        if line == "" || strings.HasPrefix(line, "//") {
-            continue
-        }
-        builder.WriteString(line)
-    }
-    return NewJson(builder.Bytes())
+    return parseJsonc(filename, stream)
 }
 
 // NewJson returns a pointer to a new `Json` object
@@ -126,6 +114,11 @@ func (j *Json) CheckGet(key string) (*Json, bool) {
     return nil, false
 }
 
+// IsNull 是否是null（或者不存在的key）
+func (j *Json) IsNull() bool {
+    return j.data == nil
+}
+
 // Map type asserts to `map`
 func (j *Json) Map() (map[string]interface{}, error) {
     if m, ok := (j.data).(map[string]interface{}); ok {
@@ -7,13 +7,21 @@ fi
 
 CURDIR=`pwd`
 OLDGOPATH="$GOPATH"
+{{if .IsModule}}
+# Go Module项目：depends通过-modfile中的replace生效，不需要设置GOPATH
+export GO111MODULE=on
+export GOBIN="$CURDIR/bin"
+{{else}}
+export GO111MODULE=off
 export GOPATH="$CURDIR:{{range .Depends}}{{.}}:{{end}}"
-
+{{end}}
 # 打开代码格式化可能会导致监控两次
 # gofmt -tabs=false -tabwidth=4 -w src
 
-go {{.GoWay}} {{.Options}} {{.MainFile}}
+go {{.GoWay}} {{range .Options}}{{.}} {{end}}{{.MainFile}}
+STATUS=$?
 
 export GOPATH="$OLDGOPATH"
 
-echo 'finished'
\ No newline at end of file
+echo 'finished'
+exit $STATUS
@@ -2,6 +2,8 @@
 
 setlocal
 
+set STATUS=1
+
 if exist install.bat goto ok
 echo install.bat must be run from its folder
 goto end
@@ -9,14 +11,22 @@ goto end
 :ok
 
 set OLDGOPATH=%GOPATH%
+{{if .IsModule}}
+::Go Module项目：depends通过-modfile中的replace生效，不需要设置GOPATH
+set GO111MODULE=on
+set GOBIN=%~dp0bin
+{{else}}
+set GO111MODULE=off
 set GOPATH=%~dp0;{{range .Depends}}{{.}};{{end}}
-
+{{end}}
 ::打开代码格式化可能会导致监控两次
 ::gofmt -tabs=false -tabwidth=4 -w src
 
-go {{.GoWay}} {{.Options}} {{.MainFile}}
+go {{.GoWay}} {{range .Options}}{{.}} {{end}}{{.MainFile}}
+set STATUS=%ERRORLEVEL%
 
 set GOPATH=%OLDGOPATH%
 
 :end
 echo finished
+exit /b %STATUS%